@@ -17,6 +17,7 @@ package main
   (see https://sourceware.org/binutils/docs-2.18/as/LNS-directives.html#LNS-directives)
 
   .file idx "path"
+  .file idx "dir" "path"   (DWARF 5)
   .loc idx line column
 
 	(c) Holger Berger 2018
@@ -25,11 +26,17 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 )
 
+// progress messages while reading files go here, discarded by batch commands
+var progress io.Writer = os.Stdout
+
 // used to jump from source into assembler
 type loctuple struct {
 	fileid, linenr int
@@ -38,15 +45,32 @@ type loctuple struct {
 // for each line we store this information, indexed by assembly file line number
 type indextuple struct {
 	loc    loctuple // source location, file and line#
-	symbol string   // last=current global symbol
+	symbol string   // function containing the line, "" outside of functions
+	loop   int      // innermost loop containing the line, index into loops + 1, 0 = no loop
 }
 
 // AssemblerFile is the class to represent the file and its locations tables
 type AssemblerFile struct {
-	filebuffer    *FileBuffer        // the associated buffer storing the file
-	filenametable []string           // table of filename
-	loctable      map[loctuple][]int // table mapping loctuple to linenumber in assembler file
-	index         []indextuple       // table of location information indexed by line number
+	filebuffer      *FileBuffer              // the associated buffer storing the file
+	filenametable   []string                 // table of filename
+	loctable        map[loctuple][]int       // table mapping loctuple to linenumber in assembler file
+	index           []indextuple             // table of location information indexed by line number
+	ve              bool                     // VE assembler, x86 otherwise
+	labels          map[string]int           // table mapping labels to linenumber
+	functions       []Function               // line ranges of functions by .type, .size and .cfi, local ones included
+	loops           []Loop                   // loops found by backward branches
+	stackslots      []StackSlot              // stack slots of all functions
+	stackaccess     map[int]StackAccess      // stack slot accesses indexed by line number
+	regvars         []RegisterVariable       // variables in registers from debug information, ordered by first line
+	regvarsat       map[int][]int            // indices into regvars by line number
+	vl              map[int]VLValue          // vector length of VE vector instructions indexed by line number
+	addresses       map[int][]RegisterSymbol // registers holding symbol addresses indexed by line number (VE)
+	findings        []Finding                // lint findings sorted by line
+	findingsat      map[int][]int            // indices into findings by line number
+	constants       map[string]*Constant     // data following labels, by label
+	dataannotations map[int]string           // decoded values of data lines and labels, by line number
+	filtered        [nrfilters][]bool        // lines hidden by each filter, computed on first use (see filter.go)
+	sections        []string                 // section of each line, computed on first use (see filter.go)
 }
 
 // NewAssemblerFile reads a file into a filebuffer
@@ -68,7 +92,7 @@ func NewAssemblerFile(filename string) (*AssemblerFile, error) {
 
 	reader := bufio.NewReaderSize(ifile, 1024*1024) // get a nice buffer
 
-	fmt.Print("Reading file...")
+	fmt.Fprint(progress, "Reading file...")
 	linecount := 1
 	for {
 		// read line from file, bail out at end of file
@@ -91,9 +115,8 @@ func NewAssemblerFile(filename string) (*AssemblerFile, error) {
 	newfile.index = make([]indextuple, linecount)
 
 	// go over all lines again
-	fmt.Println("\nIndexing file...")
+	fmt.Fprintln(progress, "\nIndexing file...")
 	curloc := loctuple{}
-	cursymbol := ""
 	// process lines
 	for cl := 1; cl < linecount; cl++ {
 		strline := newfile.filebuffer.GetLine(cl)
@@ -140,30 +163,53 @@ func NewAssemblerFile(filename string) (*AssemblerFile, error) {
 					}
 					newfile.loctable[loctuple{fileid, linenr}] = append(newfile.loctable[loctuple{fileid, linenr}], cl)
 					curloc = loctuple{fileid, linenr}
-				} else if strings.Index(strline[pos:], ".file ") != -1 {
+				} else if strings.Index(strline[pos:], ".file ") != -1 || strings.Index(strline[pos:], ".file\t") != -1 {
 					// collect filenames, the one without index is current file and in position 0
 					flds := strings.Fields(strline[pos:])
 					if len(flds) > 2 {
-						newfile.filenametable = append(newfile.filenametable, expandfilename(flds[2][1:len(flds[2])-1]))
-					} else {
-						newfile.filenametable = append(newfile.filenametable, expandfilename(flds[1][1:len(flds[1])-1]))
-					}
-				} else if strings.Index(strline[pos:], ".globl ") != -1 {
-					cursymbol = strings.Join(strings.Fields(strline[pos:])[1:], " ")
-				} else if strings.Index(strline[pos:], ".byte") != -1 {
-					flds := strings.Fields(strline[pos:])
-					v, err := strconv.Atoi(flds[1])
-					if err == nil {
-						// BUG this is a hack, we access and modify underlying data structure
-						block := (cl - 1) / lineblocksize
-						newfile.filebuffer.lineblocks[block].lines[(cl-1)%lineblocksize] = fmt.Sprintf("        .byte %3d      # '%s'", v, string(rune(v)))
+						// .file idx "name" or .file idx "dir" "name" (DWARF 5), indices can be given in any order
+						fileid, err := strconv.Atoi(flds[1])
+						name := strings.Trim(flds[2], "\"")
+						if len(flds) > 3 && flds[3][0] == '"' {
+							dir := name
+							name = strings.Trim(flds[3], "\"")
+							if dir != "" && name != "" && name[0] != '/' {
+								name = dir + "/" + name
+							}
+						}
+						if err == nil && fileid >= 0 && name != "" {
+							for len(newfile.filenametable) <= fileid {
+								newfile.filenametable = append(newfile.filenametable, "")
+							}
+							newfile.filenametable[fileid] = expandfilename(name)
+						}
+					} else if len(flds) > 1 && len(newfile.filenametable) == 0 {
+						if name := strings.Trim(flds[1], "\""); name != "" {
+							newfile.filenametable = append(newfile.filenametable, expandfilename(name))
+						}
 					}
 				}
 			} // lines with .
 		}
-		newfile.index[cl] = indextuple{curloc, cursymbol}
+		newfile.index[cl] = indextuple{curloc, "", 0} // symbols are set by buildfunctions
 	} // loop process lines
 
+	if linecount > 1 && strings.Index(newfile.filebuffer.GetLine(1), ".ident \"n") > -1 {
+		newfile.ve = true
+	}
+
+	fmt.Fprintln(progress, "Analyzing file...")
+	newfile.buildfunctions()
+	newfile.collectlabels()
+	newfile.findloops()
+	newfile.findstackslots()
+	newfile.findvariables()
+	newfile.findconstants()
+	newfile.resolveaddresses()
+	if newfile.ve {
+		newfile.tracevl()
+	}
+
 	ifile.Close()
 	return &newfile, nil
 }
@@ -171,20 +217,58 @@ func NewAssemblerFile(filename string) (*AssemblerFile, error) {
 // expandfilename prepends searchpath, so we can later just open and read
 func expandfilename(fn string) string {
 	if _, err := os.Stat(fn); err == nil {
-		fmt.Println("found source", fn)
+		fmt.Fprintln(progress, "found source", fn)
 		return fn
 	}
-	if fn[0] != '/' && len(opts.Sourcedirs) > 0 {
+	if fn != "" && fn[0] != '/' && len(opts.Sourcedirs) > 0 {
 		sp := strings.Split(opts.Sourcedirs, ",")
 		for _, p := range sp {
 			testpath := p + "/" + fn
 			//fmt.Println("trying ", testpath)
 			if _, err := os.Stat(testpath); err == nil {
-				fmt.Println("found source", fn, "at", testpath)
+				fmt.Fprintln(progress, "found source", fn, "at", testpath)
 				return testpath
 			}
 		}
 	}
-	fmt.Println("could not find source", fn)
+	fmt.Fprintln(progress, "could not find source", fn)
 	return fn
 }
+
+// asmlinesof returns the assembler lines generated for a source line, filename "" matches any file
+func (a *AssemblerFile) asmlinesof(filename string, line int) []int {
+	lines := make([]int, 0, 16)
+	// several file ids can name the same file, like file 0 and 1 with DWARF 5
+	for fileid, name := range a.filenametable {
+		if filename != "" && !samesource(name, filename) {
+			continue
+		}
+		// we use loctable to quickly jump to .loc lines and search from there
+		loc := loctuple{fileid, line}
+		end := 0
+		for _, l := range a.loctable[loc] {
+			// consecutive .loc lines of the same line were already added
+			if l < end {
+				continue
+			}
+			for end = l; end < len(a.index) && a.index[end].loc == loc; end++ {
+				lines = append(lines, end)
+			}
+		}
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+// samesource tells if a name from the file table names the file filename
+func samesource(name, filename string) bool {
+	if name == "" {
+		return false
+	}
+	if name == filename || strings.Index(filename, "/"+name) > 0 {
+		return true
+	}
+	abs1, err1 := filepath.Abs(name)
+	abs2, err2 := filepath.Abs(filename)
+	return err1 == nil && err2 == nil && abs1 == abs2
+}
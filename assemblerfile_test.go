@@ -0,0 +1,33 @@
+package main
+
+/*
+	tests of reading assembler files
+*/
+
+import "testing"
+
+func TestFileTable(t *testing.T) {
+	tests := []struct {
+		directives string
+		files      []string
+	}{
+		{"\t.file\t\"a.c\"\n\t.file 1 \"a.c\"\n\t.file 2 \"/usr/include/stdio.h\"\n", []string{"a.c", "a.c", "/usr/include/stdio.h"}},
+		// DWARF 5
+		{"\t.file\t\"a.c\"\n\t.file 0 \"/src\" \"a.c\"\n\t.file 1 \"a.c\"\n", []string{"/src/a.c", "a.c"}},
+		{"\t.file 2 \"/src\" \"/usr/include/stdio.h\"\n\t.file 1 \"\" \"x.c\"\n", []string{"", "x.c", "/usr/include/stdio.h"}},
+		{"\t.file 1 \"\"\n\t.file \"\"\n", []string{}},
+	}
+	for _, test := range tests {
+		a := testfile(t, test.directives+"\t.text\n")
+		if len(a.filenametable) != len(test.files) {
+			t.Errorf("%q: files %q, want %q", test.directives, a.filenametable, test.files)
+			continue
+		}
+		for i := range test.files {
+			if a.filenametable[i] != test.files[i] {
+				t.Errorf("%q: files %q, want %q", test.directives, a.filenametable, test.files)
+				break
+			}
+		}
+	}
+}
@@ -24,6 +24,7 @@ type AssemblerModel struct {
 	lastline      string         // caching: buffer last line
 	lastlinenr    int            // caching: buffer number of last line
 	lastcolor     int16          // caching: color number of last call
+	lastannotated int            // caching: start of annotations in last line, length of line if none
 	recomment     *regexp.Regexp // optimization: precompiled regexps
 	relabel       *regexp.Regexp
 	relocallabel  *regexp.Regexp
@@ -51,6 +52,7 @@ func (a *AssemblerModel) GetCell(x, y int) (rune, int16, goncurses.Char) {
 	if y != a.lastlinenr || a.lastlinenr == 0 {
 		a.lastline = a.file.GetLine(y)
 		a.lastlinenr = y
+		raw := a.lastline
 
 		// FIXME make those color constants names constants
 
@@ -76,6 +78,15 @@ func (a *AssemblerModel) GetCell(x, y int) (rune, int16, goncurses.Char) {
 		if m != nil {
 			a.lastcolor = 5
 		}
+		// spills and reloads inside of loops
+		if a.lastcolor == 1 && a.assemblerfile.spillinloop(y) {
+			a.lastcolor = 8
+		}
+
+		// colors are decided on the raw line, symbols are shown demangled
+		a.lastline = demangleline(raw)
+		a.lastannotated = len(a.lastline)
+		a.lastline = a.annotate(y, a.lastline)
 
 		// match registers, we attach an end marker | here, to properly match cases where a register is last argument
 		if a.reregister1 != nil {
@@ -90,8 +101,12 @@ func (a *AssemblerModel) GetCell(x, y int) (rune, int16, goncurses.Char) {
 		}
 	}
 
+	// variable names of register operands
+	if x >= a.lastannotated {
+		return rune(a.lastline[x]), 3, 0
+	}
 	// normal instructions
-	if a.lastcolor == 1 && (a.reregister1 != nil || a.reregister2 != nil) {
+	if (a.lastcolor == 1 || a.lastcolor == 8) && (a.reregister1 != nil || a.reregister2 != nil) {
 		for _, ii := range a.rematch1 {
 			if x >= ii[0] && x < ii[1] {
 				return rune(a.lastline[x]), 4, gc.A_BOLD
@@ -106,22 +121,36 @@ func (a *AssemblerModel) GetCell(x, y int) (rune, int16, goncurses.Char) {
 	return rune(a.lastline[x]), a.lastcolor, 0
 }
 
+// annotate appends decoded data values, symbol addresses and variables in the registers of the instruction to a line
+func (a *AssemblerModel) annotate(y int, line string) string {
+	if value := a.assemblerfile.dataannotation(y); value != "" {
+		line += "  # " + value
+	}
+	if addresses := a.assemblerfile.addressesof(y); addresses != "" {
+		line += "  # " + addresses
+	}
+	if showvariables {
+		if vars := a.assemblerfile.variablesof(y); vars != "" {
+			line += "  # " + vars
+		}
+	}
+	return line
+}
+
 // GetNrLines returns the number of lines in the file
 func (a *AssemblerModel) GetNrLines() int {
-	// FIXME optimize, could be pushed to filebuffer
-	block := a.file.appendin
-	return a.file.lineblocks[block].lastline
+	return a.file.GetNrLines()
 }
 
 // GetLineLen returns the length of the line (file coordinates, first =  1)
 func (a *AssemblerModel) GetLineLen(line int) int {
 	if line <= a.GetNrLines() {
-		return len(a.file.GetLine(line))
+		return len(a.annotate(line, demangleline(a.file.GetLine(line))))
 	}
 	return 0
 }
 
-// GetLine returns line without anyu processing (tabs expanded)
+// GetLine returns line without anyu processing (tabs expanded, symbols not demangled)
 func (a *AssemblerModel) GetLine(line int) string {
 	return a.file.GetLine(line)
 }
@@ -0,0 +1,97 @@
+package main
+
+/*
+	source line color banding
+
+	each source line gets a background color, the instructions
+	generated for it are drawn in the same color in the assembler
+	panels, and the source line in the source panel. neighbouring
+	source lines always have different colors, so scattered and
+	duplicated blocks of a statement can be seen without marking.
+	the color depends on the file as well, so inlined code from a
+	header does not share the color of the same line number of the
+	main file.
+*/
+
+import (
+	gc "github.com/rthornton128/goncurses"
+)
+
+const bandpair = 9 // first color pair of bands
+
+// background colors of bands, yellow is left out as it is the selection
+var bandcolors = []int16{gc.C_GREEN, gc.C_CYAN, gc.C_MAGENTA, gc.C_BLUE, gc.C_RED, gc.C_WHITE}
+
+// initbands defines the color pairs of the bands
+func initbands() {
+	for i, c := range bandcolors {
+		fg := int16(gc.C_BLACK)
+		if c == gc.C_BLUE || c == gc.C_RED {
+			fg = gc.C_WHITE
+		}
+		gc.InitPair(bandpair+int16(i), fg, c)
+	}
+}
+
+// band returns the color pair of a source line, the same line number in another file is shifted to another color
+func band(sourceid, linenr int) int16 {
+	return bandpair + int16((sourceid+linenr)%len(bandcolors))
+}
+
+// sourceid returns the first file id with the same name as fileid, like file 0 and 1 with DWARF 5
+func (a *AssemblerFile) sourceid(fileid int) int {
+	for id := 0; id < fileid && fileid < len(a.filenametable); id++ {
+		if a.filenametable[id] == a.filenametable[fileid] {
+			return id
+		}
+	}
+	return fileid
+}
+
+// bandtop returns the band color of an assembler line, 0 if it has no source line
+func bandtop(line int) int16 {
+	if line < 1 || line >= len(assemblerfile.index) || assemblerfile.index[line].loc.linenr == 0 {
+		return 0
+	}
+	loc := assemblerfile.index[line].loc
+	return band(assemblerfile.sourceid(loc.fileid), loc.linenr)
+}
+
+// generatedlines returns the band colors of the lines of a source file code was generated for
+func (a *AssemblerFile) generatedlines(filename string) map[int]int16 {
+	ids := make(map[int]int)
+	for loc := range a.loctable {
+		if loc.fileid < len(a.filenametable) && samesource(a.filenametable[loc.fileid], filename) {
+			id := a.sourceid(loc.fileid)
+			if old, ok := ids[loc.linenr]; !ok || id < old {
+				ids[loc.linenr] = id
+			}
+		}
+	}
+	lines := make(map[int]int16, len(ids))
+	for linenr, id := range ids {
+		lines[linenr] = band(id, linenr)
+	}
+	return lines
+}
+
+// bandmiddle returns the band color of a line of the source panel, 0 if no code was generated for it
+func (t *TuiT) bandmiddle(linenr int) int16 {
+	filename := t.middlemodel.GetFilename()
+	if t.bandfile != assemblerfile || t.bandsourcename != filename {
+		t.bandsource = assemblerfile.generatedlines(filename)
+		t.bandfile = assemblerfile
+		t.bandsourcename = filename
+	}
+	return t.bandsource[linenr]
+}
+
+// togglebanding switches source line color banding on or off
+func (t *TuiT) togglebanding() {
+	t.banding = !t.banding
+	t.Refreshtopall()
+	t.drawother()
+	if t.middlelines > 0 {
+		t.Refreshmiddleall()
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+/*
+	tests of source line color banding
+*/
+
+import (
+	"testing"
+)
+
+func TestBands(t *testing.T) {
+	a := testfile(t, `	.file	"a.c"
+	.text
+	.globl	f
+	.type	f, @function
+f:
+	.file 1 "a.c"
+	.file 2 "inc/h.h"
+	.file 3 "a.c"
+	.loc 1 5 0
+	movl	$1, %eax
+	.loc 2 5 0
+	addl	$2, %eax
+	.loc 1 6 0
+	addl	$3, %eax
+	.loc 3 5 0
+	ret
+	.size	f, .-f
+`)
+	saved := assemblerfile
+	assemblerfile = a
+	defer func() { assemblerfile = saved }()
+
+	first, header, next, again := linewith(t, a, "$1"), linewith(t, a, "$2"), linewith(t, a, "$3"), linewith(t, a, "ret")
+	if bandtop(first) == bandtop(header) {
+		t.Errorf("line 5 of a.c and inc/h.h have the same band")
+	}
+	if bandtop(first) == bandtop(next) {
+		t.Errorf("lines 5 and 6 of a.c have the same band")
+	}
+	if bandtop(first) != bandtop(again) {
+		t.Errorf("line 5 of a.c has different bands for file 1 and 3")
+	}
+
+	lines := a.generatedlines("src/a.c")
+	if len(lines) != 2 || lines[5] != bandtop(first) || lines[6] != bandtop(next) {
+		t.Errorf("generated lines of a.c %v", lines)
+	}
+	if lines := a.generatedlines("other/h.h"); len(lines) != 0 {
+		t.Errorf("generated lines of other/h.h %v, want none", lines)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+/*
+	basic blocks and control flow inside of a function
+
+	a block starts at the function start, at a label which is the
+	target of a branch or jump table, or after a branch, and ends
+	before the next start. labels nothing jumps to, like the .LVL
+	and .LBB labels gcc emits for debug information, do not start
+	blocks, they would split straight line code into many blocks.
+	successors are the target of the branch and the following
+	block, if the block does not end with an unconditional branch
+	or return. calls do not end a block.
+*/
+
+import (
+	"sort"
+	"strings"
+)
+
+// Block is a basic block, a range of lines without branches in between
+type Block struct {
+	start, end int   // first and last line
+	succs      []int // indices of successor blocks in the same function
+	preds      []int // indices of predecessor blocks in the same function
+}
+
+// isunconditional checks if control never falls through an instruction
+func isunconditional(ins Instruction, ve bool) bool {
+	if ve {
+		base := ins.basemnemonic()
+		if base != "b" && base != "br" && base != "bat" && base != "brat" {
+			return false
+		}
+		// b.l.at, b.l.t are always true, .nt and .t are only hints
+		for _, s := range strings.Split(ins.mnemonic, ".")[1:] {
+			if s == "af" {
+				return false
+			}
+		}
+		return true
+	}
+	return strings.HasPrefix(ins.mnemonic, "jmp") || strings.HasPrefix(ins.mnemonic, "ret") ||
+		ins.mnemonic == "ud2" || ins.mnemonic == "hlt"
+}
+
+// buildblocks splits a function into basic blocks and links them
+func (a *AssemblerFile) buildblocks(fi int) []Block {
+	f := a.functions[fi]
+	blocks := make([]Block, 0, 16)
+
+	// labels used as branch or jump table targets. other labels, like the .LVL and .LBB
+	// labels of debug information, do not start blocks, so blocks, the vector length
+	// tracking and block counts are the same for code compiled with and without -g
+	targets := make(map[string]bool)
+	for l := f.start; l <= f.end; l++ {
+		line := a.filebuffer.GetLine(l)
+		if ins, ok := parseInstruction(line); ok && isbranch(ins, a.ve) {
+			targets[a.branchtarget(ins)] = true
+		} else if fields := strings.Fields(line); len(fields) > 1 && (fields[0] == ".long" || fields[0] == ".quad") {
+			// jump tables
+			for _, label := range strings.FieldsFunc(fields[1], func(r rune) bool { return r == '-' || r == ',' }) {
+				targets[label] = true
+			}
+		}
+	}
+	delete(targets, "")
+
+	// first pass, find leaders
+	leader := true
+	for l := f.start; l <= f.end; l++ {
+		line := a.filebuffer.GetLine(l)
+		if len(line) > 0 && line[0] != ' ' && line[0] != '#' && targets[labelof(line)] {
+			leader = true
+		}
+		if leader {
+			if len(blocks) > 0 {
+				blocks[len(blocks)-1].end = l - 1
+			}
+			blocks = append(blocks, Block{start: l})
+			leader = false
+		}
+		if ins, ok := parseInstruction(line); ok && isbranch(ins, a.ve) {
+			leader = true
+		}
+	}
+	if len(blocks) == 0 {
+		return blocks
+	}
+	blocks[len(blocks)-1].end = f.end
+
+	// second pass, successors from last instruction of each block
+	for bi := range blocks {
+		var last Instruction
+		found := false
+		for l := blocks[bi].end; l >= blocks[bi].start; l-- {
+			if ins, ok := parseInstruction(a.filebuffer.GetLine(l)); ok {
+				last = ins
+				found = true
+				break
+			}
+		}
+		fallthru := true
+		if found && isbranch(last, a.ve) {
+			if target := a.branchtarget(last); target != "" {
+				if ti := blockof(blocks, a.labels[target]); ti != -1 {
+					blocks[bi].succs = append(blocks[bi].succs, ti)
+				}
+			}
+			fallthru = !isunconditional(last, a.ve)
+		} else if found && !a.ve && strings.HasPrefix(last.mnemonic, "ret") {
+			fallthru = false
+		}
+		if fallthru && bi+1 < len(blocks) {
+			blocks[bi].succs = append(blocks[bi].succs, bi+1)
+		}
+	}
+	for bi := range blocks {
+		for _, s := range blocks[bi].succs {
+			blocks[s].preds = append(blocks[s].preds, bi)
+		}
+	}
+	return blocks
+}
+
+// blockof returns the index of the block containing line, or -1
+func blockof(blocks []Block, line int) int {
+	i := sort.Search(len(blocks), func(i int) bool { return blocks[i].end >= line })
+	if i < len(blocks) && blocks[i].start <= line {
+		return i
+	}
+	return -1
+}
@@ -0,0 +1,280 @@
+package main
+
+/*
+	check mode for continuous integration
+
+		veass check spec.yaml foo.s
+
+	checks expectations about the generated code, prints one line
+	per expectation and exits with 1 if one of them fails, with 2
+	if spec or assembler file can not be read.
+	the spec is a list of expectations in a small subset of YAML,
+	each entry has a function or a source line and one or more checks:
+
+		- function: daxpy_        # global symbol
+		  loop-contains: vfmad    # a loop in the function contains the mnemonic
+		- function: kernel
+		  not-contains: vgt       # no such instruction in the function
+		- function: foo
+		  contains: call          # function contains the mnemonic
+		  instructions: < 200     # instruction count, operators < <= > >= == !=
+		  loops: ">= 1"           # loop count
+		- line: 42                # source line
+		  file: foo.c             # optional, name of source file
+		  vector: true            # line produces vector code (or false)
+
+	mnemonics are regular expressions matched against the whole mnemonic
+	and the mnemonic without suffixes, so vfmad matches vfmad.d.
+	file is matched with samesource, like the source view does.
+*/
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Expectation is one entry of a spec file
+type Expectation struct {
+	linenr int               // line in spec file
+	keys   []string          // keys in order of appearance
+	values map[string]string // values by key
+}
+
+// readspec parses a spec file
+func readspec(r io.Reader, filename string) ([]Expectation, error) {
+	expectations := make([]Expectation, 0, 16)
+	scanner := bufio.NewScanner(r)
+	linenr := 0
+	for scanner.Scan() {
+		linenr++
+		line := scanner.Text()
+		if pos := strings.Index(line, " #"); pos != -1 {
+			line = line[:pos]
+		}
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed[0] == '#' || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "- ") {
+			expectations = append(expectations, Expectation{linenr: linenr, values: make(map[string]string)})
+			trimmed = strings.TrimSpace(trimmed[2:])
+		} else if len(expectations) == 0 || line[0] != ' ' && line[0] != '\t' {
+			return nil, fmt.Errorf("%s:%d: expected list entry starting with '- '", filename, linenr)
+		}
+		pos := strings.Index(trimmed, ":")
+		if pos == -1 {
+			return nil, fmt.Errorf("%s:%d: expected 'key: value'", filename, linenr)
+		}
+		key := strings.TrimSpace(trimmed[:pos])
+		value := strings.Trim(strings.TrimSpace(trimmed[pos+1:]), `"'`)
+		e := &expectations[len(expectations)-1]
+		e.keys = append(e.keys, key)
+		e.values[key] = value
+	}
+	return expectations, scanner.Err()
+}
+
+// compare checks value against a condition like "< 200", a plain number means ==
+func compare(value int, condition string) (bool, error) {
+	condition = strings.TrimSpace(condition)
+	op := strings.TrimRight(condition[:len(condition)-len(strings.TrimLeft(condition, "<>=!"))], " ")
+	limit, err := strconv.Atoi(strings.TrimSpace(condition[len(op):]))
+	if err != nil {
+		return false, fmt.Errorf("bad condition %q", condition)
+	}
+	switch op {
+	case "<":
+		return value < limit, nil
+	case "<=":
+		return value <= limit, nil
+	case ">":
+		return value > limit, nil
+	case ">=":
+		return value >= limit, nil
+	case "", "==":
+		return value == limit, nil
+	case "!=":
+		return value != limit, nil
+	}
+	return false, fmt.Errorf("bad operator %q", op)
+}
+
+// matchmnemonic returns a function checking instructions against a mnemonic expression
+func matchmnemonic(expr string) (func(Instruction) bool, error) {
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return nil, err
+	}
+	return func(ins Instruction) bool {
+		return re.MatchString(ins.mnemonic) || re.MatchString(ins.basemnemonic())
+	}, nil
+}
+
+// functionbyname returns the index of the function with name, -1 if not found
+func (a *AssemblerFile) functionbyname(name string) int {
+	for fi, f := range a.functions {
+		if f.name == name || demangle(f.name) == name {
+			return fi
+		}
+	}
+	return -1
+}
+
+// containsinstruction returns the first line from..to with a matching instruction, or -1
+func (a *AssemblerFile) containsinstruction(from, to int, match func(Instruction) bool) int {
+	for l := from; l <= to; l++ {
+		if ins, ok := parseInstruction(a.filebuffer.GetLine(l)); ok && match(ins) {
+			return l
+		}
+	}
+	return -1
+}
+
+// checkfunction checks one key of an expectation for a function, returns a description of the result
+func (a *AssemblerFile) checkfunction(fi int, key, value string) (bool, string, error) {
+	f := a.functions[fi]
+	switch key {
+	case "contains", "not-contains", "loop-contains":
+		match, err := matchmnemonic(value)
+		if err != nil {
+			return false, "", err
+		}
+		if key == "loop-contains" {
+			for _, li := range f.loops {
+				if l := a.containsinstruction(a.loops[li].head, a.loops[li].latch, match); l != -1 {
+					return true, fmt.Sprintf("loop %s contains %s in line %d", a.loopname(li), value, l), nil
+				}
+			}
+			return false, fmt.Sprintf("no loop contains %s", value), nil
+		}
+		l := a.containsinstruction(f.start, f.end, match)
+		if l == -1 {
+			return key == "not-contains", fmt.Sprintf("no %s", value), nil
+		}
+		return key == "contains", fmt.Sprintf("%s in line %d", value, l), nil
+	case "instructions", "loops":
+		count := f.instructions
+		if key == "loops" {
+			count = len(f.loops)
+		}
+		ok, err := compare(count, value)
+		return ok, fmt.Sprintf("%d %s, expected %s", count, key, value), err
+	}
+	return false, "", fmt.Errorf("unknown check %q", key)
+}
+
+// checkline checks if a source line produces vector code
+func (a *AssemblerFile) checkline(linenr int, file, value string) (bool, string, error) {
+	want, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, "", fmt.Errorf("vector must be true or false")
+	}
+	found, vector := false, -1
+	for _, l := range a.asmlinesof(file, linenr) {
+		ins, ok := parseInstruction(a.filebuffer.GetLine(l))
+		if !ok {
+			continue
+		}
+		found = true
+		if isvectorclass(a.classify(ins)) {
+			vector = l
+			break
+		}
+	}
+	switch {
+	case !found:
+		return false, "no code for source line", nil
+	case vector != -1:
+		return want, fmt.Sprintf("vector code in line %d", vector), nil
+	}
+	return !want, "only scalar code", nil
+}
+
+// check runs one expectation, returns if it passed and a description
+func (a *AssemblerFile) check(e Expectation) (bool, string, error) {
+	if name, ok := e.values["function"]; ok {
+		fi := a.functionbyname(name)
+		if fi == -1 {
+			return false, "function " + name + ": not found", nil
+		}
+		passed := true
+		results := make([]string, 0, len(e.keys))
+		for _, key := range e.keys {
+			if key == "function" {
+				continue
+			}
+			ok, result, err := a.checkfunction(fi, key, e.values[key])
+			if err != nil {
+				return false, "", err
+			}
+			passed = passed && ok
+			results = append(results, result)
+		}
+		return passed, "function " + name + ": " + strings.Join(results, ", "), nil
+	}
+	if line, ok := e.values["line"]; ok {
+		linenr, err := strconv.Atoi(line)
+		if err != nil {
+			return false, "", fmt.Errorf("bad line %q", line)
+		}
+		if _, ok := e.values["vector"]; !ok {
+			return false, "", fmt.Errorf("line needs a vector check")
+		}
+		passed, result, err := a.checkline(linenr, e.values["file"], e.values["vector"])
+		return passed, "source line " + line + ": " + result, err
+	}
+	return false, "", fmt.Errorf("expectation needs function or line")
+}
+
+// runcheck implements the check command, returns the exit code
+func runcheck(args []string) int {
+	if len(args) != 2 {
+		fmt.Println("usage: veass check <spec.yaml> <file.s>")
+		return 2
+	}
+	progress = ioutil.Discard
+
+	specfile, err := os.Open(args[0])
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	expectations, err := readspec(specfile, args[0])
+	specfile.Close()
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+
+	a, err := NewAssemblerFile(args[1])
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+
+	failed := 0
+	for _, e := range expectations {
+		passed, result, err := a.check(e)
+		if err != nil {
+			fmt.Printf("%s:%d: %s\n", args[0], e.linenr, err)
+			return 2
+		}
+		status := "ok  "
+		if !passed {
+			status = "FAIL"
+			failed++
+		}
+		fmt.Printf("%s:%d: %s %s\n", args[0], e.linenr, status, result)
+	}
+	fmt.Printf("%d of %d expectations failed\n", failed, len(expectations))
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
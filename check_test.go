@@ -0,0 +1,89 @@
+package main
+
+/*
+	tests of the spec parser and the check command
+*/
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestReadSpec(t *testing.T) {
+	tests := []struct {
+		spec string
+		keys []string // keys of all entries, entries separated by "-"
+		err  bool
+	}{
+		{"- function: daxpy\n  loop-contains: vfmad\n", []string{"function", "loop-contains"}, false},
+		{"---\n# comment\n- line: 5   # source line\n  vector: true\n- function: f\n",
+			[]string{"line", "vector", "-", "function"}, false},
+		{"", nil, false},
+		{"function: daxpy\n", nil, true},
+		{"- function daxpy\n", nil, true},
+		{"- function: f\ncontains: call\n", nil, true},
+	}
+	for _, test := range tests {
+		expectations, err := readspec(strings.NewReader(test.spec), "spec.yaml")
+		if (err != nil) != test.err {
+			t.Errorf("readspec(%q) error %v, want error %v", test.spec, err, test.err)
+			continue
+		}
+		keys := []string{}
+		for i, e := range expectations {
+			if i > 0 {
+				keys = append(keys, "-")
+			}
+			keys = append(keys, e.keys...)
+		}
+		if strings.Join(keys, " ") != strings.Join(test.keys, " ") {
+			t.Errorf("readspec(%q) keys %v, want %v", test.spec, keys, test.keys)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	asm, err := ioutil.TempFile("", "veass*.s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(asm.Name())
+	asm.WriteString(vesample)
+	asm.Close()
+
+	tests := []struct {
+		spec string
+		exit int
+	}{
+		{"- function: daxpy\n  loop-contains: vfmad\n  contains: bsic\n  loops: 1\n", 0},
+		{"- function: helper\n  not-contains: vgt|vsc\n  instructions: < 10\n", 0},
+		{"- line: 5\n  file: ve.c\n  vector: true\n- line: 4\n  vector: false\n", 0},
+		{"- function: daxpy\n  not-contains: vfmad\n", 1},
+		{"- function: missing\n  contains: ld\n", 1},
+		{"- line: 5\n  file: src/ve.c\n  vector: true\n", 0},
+		{"- line: 5\n  file: other.c\n  vector: true\n", 1},
+		{"- line: 4\n  vector: true\n", 1},
+		{"- function: daxpy\n  loops: about 1\n", 2},
+		{"- function: daxpy\n  contains: (\n", 2},
+		{"- line: 5\n", 2},
+		{"- vector: true\n", 2},
+		{"function: daxpy\n", 2},
+	}
+	for _, test := range tests {
+		spec, err := ioutil.TempFile("", "veass*.yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		spec.WriteString(test.spec)
+		spec.Close()
+		if exit := runcheck([]string{spec.Name(), asm.Name()}); exit != test.exit {
+			t.Errorf("check of %q exits with %d, want %d", test.spec, exit, test.exit)
+		}
+		os.Remove(spec.Name())
+	}
+	if exit := runcheck([]string{"missing.yaml", asm.Name()}); exit != 2 {
+		t.Errorf("check with missing spec exits with %d, want 2", exit)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+/*
+	classification of instructions
+
+	instructions are put into a few classes, which is all the
+	loop and function summaries need to know about them.
+	for VE, the class is derived from the descriptions in veops,
+	so "= Vector Gather" makes vgt a gather. for x86, the class is
+	derived from mnemonic, register class and memory operands.
+*/
+
+import (
+	"strings"
+)
+
+// instruction classes
+const (
+	classScalar      = iota // scalar integer and logic
+	classScalarFP           // scalar floating point
+	classLoad               // scalar load
+	classStore              // scalar store
+	classBranch             // branches and returns
+	classCall               // calls
+	classVector             // vector arithmetic
+	classVectorLoad         // vector load with stride
+	classVectorStore        // vector store with stride
+	classGather             // vector gather
+	classScatter            // vector scatter
+	classMask               // mask operations
+	nrclasses
+)
+
+var classnames = [nrclasses]string{
+	"scalar", "scalar-fp", "load", "store", "branch", "call",
+	"vector", "vector-load", "vector-store", "gather", "scatter", "mask",
+}
+
+var veclassops *Opstable // opstable used for classification, created on first use
+
+// lookup finds the description of a mnemonic, removing suffixes from the end until found
+func (o *Opstable) lookup(mnemonic string) string {
+	for {
+		if e := o.getops(mnemonic); e != "" {
+			return e
+		}
+		pos := strings.LastIndexByte(mnemonic, '.')
+		if pos <= 0 {
+			return ""
+		}
+		mnemonic = mnemonic[:pos]
+	}
+}
+
+// classifyVE returns the class of a VE instruction
+func classifyVE(ins Instruction) int {
+	if veclassops == nil {
+		veclassops = NewOpstableVE()
+	}
+	if ins.mnemonic == "bsic" {
+		return classCall
+	}
+	if isbranch(ins, true) {
+		return classBranch
+	}
+	switch ins.basemnemonic() {
+	case "ld", "ldu", "ldl", "ld2b", "ld1b", "dld", "dldu", "dldl", "lhm", "pfch":
+		return classLoad
+	case "st", "stu", "stl", "st2b", "st1b", "shm":
+		return classStore
+	}
+	desc := veclassops.lookup(ins.mnemonic)
+	if pos := strings.Index(desc, " = "); pos != -1 {
+		desc = desc[pos+3:]
+	}
+	switch {
+	case desc == "":
+		if isvector(ins) {
+			return classVector
+		}
+		return classScalar
+	case strings.Contains(desc, "Gather"):
+		return classGather
+	case strings.Contains(desc, "Scatter"):
+		return classScatter
+	case strings.HasPrefix(desc, "Vector Load"):
+		return classVectorLoad
+	case strings.HasPrefix(desc, "Vector Store"):
+		return classVectorStore
+	case strings.Contains(desc, "Form Mask") || strings.Contains(desc, " VM"):
+		return classMask
+	case strings.HasPrefix(desc, "Vector") || strings.Contains(desc, " to V") || strings.Contains(desc, "V to S"):
+		return classVector
+	case strings.HasPrefix(desc, "Floating") || strings.HasPrefix(desc, "Convert"):
+		return classScalarFP
+	}
+	return classScalar
+}
+
+// x86 SIMD widths
+const (
+	widthNone   = iota // no floating point or SIMD register involved
+	widthScalar        // scalar ss/sd operations
+	width128
+	width256
+	width512
+	nrwidths
+)
+
+var widthnames = [nrwidths]string{"-", "scalar", "128", "256", "512"}
+
+// simdwidth returns the SIMD width of an x86 instruction from mnemonic and register class
+func simdwidth(ins Instruction) int {
+	xmm := false
+	for _, op := range ins.operands {
+		switch {
+		case strings.Contains(op, "%zmm"):
+			return width512
+		case strings.Contains(op, "%ymm"):
+			return width256
+		case strings.Contains(op, "%xmm"):
+			xmm = true
+		}
+	}
+	if !xmm {
+		return widthNone
+	}
+	if strings.HasSuffix(ins.mnemonic, "ss") || strings.HasSuffix(ins.mnemonic, "sd") || strings.HasSuffix(ins.mnemonic, "sh") {
+		return widthScalar
+	}
+	return width128
+}
+
+// masked checks for AVX-512 masking with a mask register other than %k0
+func masked(ins Instruction) bool {
+	for _, op := range ins.operands {
+		if pos := strings.Index(op, "{%k"); pos != -1 && !strings.HasPrefix(op[pos:], "{%k0}") {
+			return true
+		}
+	}
+	return false
+}
+
+// memoryaccess checks if an x86 instruction reads or writes memory,
+// a memory operand in last position is written unless the instruction only reads it, like compares
+func memoryaccess(ins Instruction) (load, store bool) {
+	if strings.HasPrefix(ins.mnemonic, "lea") || strings.HasPrefix(ins.mnemonic, "nop") {
+		return false, false
+	}
+	last := len(ins.operands) - 1
+	for i, op := range ins.operands {
+		if strings.IndexByte(op, '(') != -1 {
+			if i == last && last > 0 && !readonlyx86(ins.mnemonic) {
+				store = true
+			} else {
+				load = true
+			}
+		}
+	}
+	return load, store
+}
+
+// classifyX86 returns the class of an x86 instruction
+func classifyX86(ins Instruction) int {
+	m := ins.mnemonic
+	switch {
+	case m[0] == 'j' || strings.HasPrefix(m, "ret"):
+		return classBranch
+	case strings.HasPrefix(m, "call"):
+		return classCall
+	case strings.Contains(m, "gather"):
+		return classGather
+	case strings.Contains(m, "scatter"):
+		return classScatter
+	case m[0] == 'k' && len(m) > 3:
+		return classMask
+	}
+	load, store := memoryaccess(ins)
+	switch simdwidth(ins) {
+	case width128, width256, width512:
+		if store {
+			return classVectorStore
+		}
+		if load {
+			return classVectorLoad
+		}
+		return classVector
+	case widthScalar:
+		if strings.HasPrefix(m, "mov") || strings.HasPrefix(m, "vmov") {
+			if store {
+				return classStore
+			}
+			if load {
+				return classLoad
+			}
+		}
+		return classScalarFP
+	}
+	if strings.HasPrefix(m, "mov") || strings.HasPrefix(m, "push") || strings.HasPrefix(m, "pop") {
+		if store {
+			return classStore
+		}
+		if load {
+			return classLoad
+		}
+	}
+	return classScalar
+}
+
+// classify returns the class of an instruction for the architecture of the file
+func (a *AssemblerFile) classify(ins Instruction) int {
+	if a.ve {
+		return classifyVE(ins)
+	}
+	return classifyX86(ins)
+}
+
+// isvectorclass checks if a class is vector code
+func isvectorclass(class int) bool {
+	return class >= classVector && class <= classMask
+}
@@ -0,0 +1,159 @@
+package main
+
+/*
+	side by side comparison of two assembler files
+
+		veass ve.s x86.s
+
+	shows both files generated from the same source next to each
+	other in the top area. the panel with the focus is always the
+	top panel of TuiT, the other one is kept in a topview and
+	swapped in when it gets the focus (TAB), so all functions
+	of the top panel work for both files.
+	the panels are synced through the source line: moving in one
+	panel marks and shows the lines the other compiler generated
+	for the same source line, moving in the source view does this
+	for both panels.
+*/
+
+import (
+	gc "github.com/rthornton128/goncurses"
+)
+
+// topview is the state of an assembler panel which is not in top
+type topview struct {
+	top         *gc.Window
+	topbar      *gc.Window
+	toptopline  int
+	topcursor   int
+	topmarked   map[int]bool
+	topmodel    PanelModel
+	toprows     []viewrow
+	interleaved bool
+	filters     [nrfilters]bool
+	folds       map[int]Fold
+	file        *AssemblerFile
+}
+
+// opencompare adds a second assembler panel for file
+func (t *TuiT) opencompare(file *AssemblerFile) {
+	var err error
+	t.other = &topview{toptopline: 1, topmarked: make(map[int]bool), topmodel: NewAssemblerModel(file), file: file}
+	t.other.top, err = gc.NewWindow(t.toplines, t.maxx-t.maxx/2, 0, t.maxx/2)
+	if err != nil {
+		panic(err)
+	}
+	t.other.top.Keypad(true)
+	t.other.top.Color(1)
+	t.other.top.ScrollOk(true)
+	t.other.topbar, err = gc.NewWindow(1, t.maxx-t.maxx/2, t.toplines, t.maxx/2)
+	if err != nil {
+		panic(err)
+	}
+	t.layouttop()
+}
+
+// layouttop places the top panel, and the other panel if open, next to each other
+func (t *TuiT) layouttop() {
+	if t.other == nil {
+		t.topwidth = t.maxx
+		t.top.Resize(t.toplines, t.maxx)
+		t.topbar.Resize(1, t.maxx)
+		t.topbar.MoveWindow(t.toplines, 0)
+		return
+	}
+	t.topwidth = t.maxx / 2
+	left, leftbar, right, rightbar := t.top, t.topbar, t.other.top, t.other.topbar
+	if t.otherfocused {
+		left, leftbar, right, rightbar = right, rightbar, left, leftbar
+	}
+	left.Resize(t.toplines, t.topwidth)
+	left.MoveWindow(0, 0)
+	leftbar.Resize(1, t.topwidth)
+	leftbar.MoveWindow(t.toplines, 0)
+	right.Resize(t.toplines, t.topwidth)
+	right.MoveWindow(0, t.maxx-t.topwidth)
+	rightbar.Resize(1, t.topwidth)
+	rightbar.MoveWindow(t.toplines, t.maxx-t.topwidth)
+}
+
+// swapother exchanges top and other panel
+func (t *TuiT) swapother() {
+	o := t.other
+	t.top, o.top = o.top, t.top
+	t.topbar, o.topbar = o.topbar, t.topbar
+	t.toptopline, o.toptopline = o.toptopline, t.toptopline
+	t.topcursor, o.topcursor = o.topcursor, t.topcursor
+	t.topmarked, o.topmarked = o.topmarked, t.topmarked
+	t.topmodel, o.topmodel = o.topmodel, t.topmodel
+	t.toprows, o.toprows = o.toprows, t.toprows
+	t.interleaved, o.interleaved = o.interleaved, t.interleaved
+	t.filters, o.filters = o.filters, t.filters
+	t.folds, o.folds = o.folds, t.folds
+	assemblerfile, o.file = o.file, assemblerfile
+}
+
+// focusother moves the focus between top and other panel
+func (t *TuiT) focusother() {
+	t.swapother()
+	t.otherfocused = !t.otherfocused
+	t.drawother()
+	t.Refreshtopall()
+}
+
+// drawother redraws the other panel without focus, if there is one
+func (t *TuiT) drawother() {
+	if t.other == nil {
+		return
+	}
+	t.swapother()
+	focus := t.focus
+	t.focus = -1
+	t.top.Touch()
+	t.Refreshtopall()
+	t.focus = focus
+	t.swapother()
+}
+
+// showsourceline marks and shows the lines of top generated for a source line
+func (t *TuiT) showsourceline(filename string, linenr int) {
+	t.topmarked = make(map[int]bool)
+	lines := assemblerfile.asmlinesof(filename, linenr)
+	for _, l := range lines {
+		t.topmarked[l] = true
+	}
+	if len(lines) > 0 {
+		t.showlinetop(lines[0])
+	}
+	t.refreshtop()
+	t.refreshtopbar()
+}
+
+// syncother shows in the other panel and the source view what was generated for the current line of top
+func (t *TuiT) syncother() {
+	filename, linenr := t.topmodel.GetPosition(t.cursortop())
+	if linenr == 0 {
+		return
+	}
+	t.swapother()
+	focus := t.focus
+	t.focus = -1
+	t.showsourceline(filename, linenr)
+	t.focus = focus
+	t.swapother()
+	if t.middlelines > 0 && samesource(filename, t.middlemodel.GetFilename()) {
+		t.middlemarked = map[int]bool{linenr: true}
+		t.showlinemiddle(linenr)
+	}
+	gc.Update()
+}
+
+// syncsource shows in both panels what was generated for the current line of the source view
+func (t *TuiT) syncsource() {
+	filename, linenr := t.middlemodel.GetFilename(), t.middletopline+t.middlecursor
+	t.showsourceline(filename, linenr)
+	t.swapother()
+	t.showsourceline(filename, linenr)
+	t.swapother()
+	gc.Update()
+}
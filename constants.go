@@ -0,0 +1,383 @@
+package main
+
+/*
+	decoded data values
+
+	the data following a label, like the constant pools .LC0 of
+	gcc, is decoded into the values it represents: pairs of .long
+	are doubles, strings are shown as strings, vectors of integers
+	as lists. whether data are floating point is taken from the
+	instructions loading them (movsd, mulpd, ...), else from the
+	section (.rodata.cst4 holds floats, .rodata.cst8 doubles).
+	the values are shown as annotations of the label line, data
+	lines and immediates of instructions get the value in the other
+	base, printable bytes their character. the lines themselves
+	are not changed.
+*/
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maximum number of elements shown for vectors
+const maxelements = 8
+
+// Constant is the data following a label
+type Constant struct {
+	label       string
+	line, last  int    // line of the label and last data line
+	section     string // section of the data
+	data        []byte // data in memory order
+	width       int    // bytes of the data directives, 0 if mixed
+	pairs       bool   // only .long directives, as compilers write doubles
+	text        bool   // only strings and bytes
+	symbolic    bool   // contains addresses, like jump tables
+	elementtype string // double, float or int, from instructions using it, "" if not known
+}
+
+// append adds the values of a data directive to a constant, false if it is not a data directive
+func (c *Constant) append(directive, rest string) bool {
+	switch directive {
+	case ".string", ".asciz", ".ascii":
+		text, _ := unquote(rest)
+		c.data = append(c.data, text...)
+		if directive != ".ascii" {
+			c.data = append(c.data, 0)
+		}
+		c.width = mixedwidth(c.width, 1)
+		return true
+	case ".zero", ".skip":
+		if n, ok := parseint(strings.TrimSpace(strings.Split(rest, ",")[0])); ok && n >= 0 && n < 1<<16 {
+			c.data = append(c.data, make([]byte, n)...)
+		}
+		c.text = false
+		return true
+	}
+	size, ok := datasizes[directive]
+	if !ok {
+		return false
+	}
+	if directive != ".byte" {
+		c.text = false
+	}
+	if directive != ".long" {
+		c.pairs = false
+	}
+	c.width = mixedwidth(c.width, size)
+	for _, value := range strings.Split(rest, ",") {
+		item := parsedataitem(value, size, false, false)
+		if item.expr != "" {
+			c.symbolic = true
+		}
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], uint64(item.value))
+		c.data = append(c.data, buf[:size]...)
+	}
+	return true
+}
+
+// mixedwidth returns the common width of data directives, 0 if they differ, -1 is no directive yet
+func mixedwidth(width, size int) int {
+	if width == -1 || width == size {
+		return size
+	}
+	return 0
+}
+
+// splitdirective returns directive and operands of a directive line, with comments removed
+func splitdirective(line string) (string, string) {
+	line = strings.TrimSpace(line)
+	if len(line) == 0 || line[0] != '.' {
+		return "", ""
+	}
+	pos := strings.IndexAny(line, " \t")
+	if pos == -1 {
+		return line, ""
+	}
+	directive, rest := line[:pos], strings.TrimSpace(line[pos:])
+	if rest != "" && rest[0] != '"' {
+		if c := strings.IndexByte(rest, '#'); c >= 0 {
+			rest = strings.TrimSpace(rest[:c])
+		}
+	}
+	return directive, rest
+}
+
+// findconstants collects the data following labels outside of debug sections
+// and the annotations of data lines
+func (a *AssemblerFile) findconstants() {
+	a.constants = make(map[string]*Constant)
+	a.dataannotations = make(map[int]string)
+	sections := a.sectionlines()
+	var c *Constant
+	run := 0 // first line of a run of .byte lines
+	var runbytes []byte
+	endrun := func() {
+		// runs inside of a constant are shown at its label
+		if len(runbytes) >= 4 && printable(runbytes) && (c == nil || !c.text) {
+			a.dataannotations[run] = strconv.Quote(strings.TrimSuffix(string(runbytes), "\x00"))
+		}
+		runbytes = runbytes[:0]
+	}
+	for l := 1; l < len(a.index); l++ {
+		line := a.filebuffer.GetLine(l)
+		if strings.HasPrefix(sections[l], ".debug") {
+			c = nil
+			continue
+		}
+		if len(line) > 0 && line[0] != ' ' && line[0] != '\t' {
+			if label := labelof(line); label != "" {
+				endrun()
+				c = &Constant{label: label, line: l, section: sections[l], width: -1, pairs: true, text: true}
+				a.constants[label] = c
+			}
+			continue
+		}
+		directive, rest := splitdirective(line)
+		if directive == ".byte" {
+			if len(runbytes) == 0 {
+				run = l
+			}
+			if v, ok := parseint(rest); ok {
+				runbytes = append(runbytes, byte(v))
+			} else {
+				endrun()
+			}
+		} else if directive != "" {
+			endrun()
+		}
+		if c != nil && !c.append(directive, rest) {
+			switch directive {
+			case ".align", ".p2align", ".balign":
+			default:
+				c = nil
+			}
+		} else if c != nil {
+			c.last = l
+		}
+		if v, ok := parseint(rest); ok && datasizes[directive] > 0 {
+			text := otherbase(rest, v)
+			if directive == ".byte" && v >= ' ' && v < 127 {
+				text = strconv.QuoteRune(rune(v))
+			}
+			if text != "" {
+				a.dataannotations[l] = text
+			}
+		}
+	}
+	endrun()
+	for label, c := range a.constants {
+		if c.last == 0 {
+			delete(a.constants, label)
+		}
+	}
+	a.constantuses()
+	for _, c := range a.constants {
+		if text := c.decode(); text != "" {
+			a.dataannotations[c.line] = text
+		}
+	}
+}
+
+// constantuses sets the element type of constants from the first instruction using them that tells it (x86)
+func (a *AssemblerFile) constantuses() {
+	if a.ve {
+		return
+	}
+	for l := 1; l < len(a.index); l++ {
+		ins, ok := parseInstruction(a.filebuffer.GetLine(l))
+		if !ok {
+			continue
+		}
+		for _, label := range a.constantrefs(ins) {
+			if c := a.constants[label]; c.elementtype == "" {
+				c.elementtype = x86usetype(ins)
+			}
+		}
+	}
+}
+
+// constantrefs returns the labels of constants referenced by an instruction
+func (a *AssemblerFile) constantrefs(ins Instruction) []string {
+	refs := make([]string, 0, 1)
+	for _, operand := range ins.operands {
+		for _, ref := range resymbolref.FindAllString(operand, -1) {
+			if pos := strings.IndexByte(ref, '@'); pos > 0 {
+				ref = ref[:pos] // VE .LC0@lo, x86 name@GOTPCREL
+			}
+			if _, ok := a.constants[ref]; ok {
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs
+}
+
+// x86usetype returns the type of data an x86 instruction works on: double or float for floating point
+// instructions, int for loads into general purpose registers, "" if it does not tell,
+// like movq into %xmm0 which moves doubles as well
+func x86usetype(ins Instruction) string {
+	switch {
+	case strings.HasSuffix(ins.mnemonic, "sd") || strings.HasSuffix(ins.mnemonic, "pd"):
+		return "double"
+	case strings.HasSuffix(ins.mnemonic, "ss") || strings.HasSuffix(ins.mnemonic, "ps"):
+		return "float"
+	}
+	for _, operand := range ins.operands {
+		if strings.HasPrefix(operand, "%") && !strings.Contains(operand, "mm") {
+			return "int"
+		}
+	}
+	return ""
+}
+
+// kind returns what the data of a constant are: string, double, float or int
+func (c *Constant) kind() string {
+	switch {
+	case c.text && len(c.data) > 1 && printable(c.data):
+		return "string"
+	case c.elementtype != "":
+		return c.elementtype
+	case strings.HasPrefix(c.section, ".rodata.cst4"):
+		return "float"
+	case strings.HasPrefix(c.section, ".rodata.cst8"), c.pairs && len(c.data) == 8:
+		return "double"
+	}
+	return "int"
+}
+
+// decode returns the values of a constant as text, "" if they can not be decoded
+func (c *Constant) decode() string {
+	if c.symbolic || len(c.data) == 0 {
+		return ""
+	}
+	kind := c.kind()
+	elements := make([]string, 0, maxelements)
+	size := c.width
+	switch kind {
+	case "string":
+		return "string " + strconv.Quote(strings.TrimSuffix(string(c.data), "\x00"))
+	case "double":
+		size = 8
+	case "float":
+		size = 4
+	default:
+		if size <= 0 {
+			return ""
+		}
+		kind = fmt.Sprintf("int%d", size*8)
+	}
+	if len(c.data)%size != 0 {
+		return ""
+	}
+	for i := 0; i < len(c.data); i += size {
+		bits := uint64(0)
+		for b := size - 1; b >= 0; b-- {
+			bits = bits<<8 | uint64(c.data[i+b])
+		}
+		switch kind {
+		case "double":
+			elements = append(elements, strconv.FormatFloat(math.Float64frombits(bits), 'g', -1, 64))
+		case "float":
+			elements = append(elements, strconv.FormatFloat(float64(math.Float32frombits(uint32(bits))), 'g', -1, 32))
+		default:
+			elements = append(elements, strconv.FormatInt(int64(bits<<(64-8*uint(size)))>>(64-8*uint(size)), 10))
+		}
+	}
+	return kind + " " + formatelements(elements)
+}
+
+// formatelements returns one value, n x value for equal values, or a list
+func formatelements(elements []string) string {
+	if len(elements) == 1 {
+		return elements[0]
+	}
+	equal := true
+	for _, e := range elements {
+		equal = equal && e == elements[0]
+	}
+	if equal {
+		return fmt.Sprintf("%d x %s", len(elements), elements[0])
+	}
+	if len(elements) > maxelements {
+		return "{" + strings.Join(elements[:maxelements], ", ") + ", ...}"
+	}
+	return "{" + strings.Join(elements, ", ") + "}"
+}
+
+// printable checks if bytes are UTF-8 text, a terminating 0 is allowed
+func printable(data []byte) bool {
+	data = []byte(strings.TrimSuffix(string(data), "\x00"))
+	if len(data) == 0 {
+		return false
+	}
+	for _, b := range data {
+		if b < ' ' && b != '\n' && b != '\t' || b == 127 {
+			return false
+		}
+	}
+	return utf8.Valid(data)
+}
+
+// otherbase returns a number written in decimal in hex and vice versa, "" for small numbers
+func otherbase(written string, v int64) string {
+	if v > -10 && v < 10 {
+		return ""
+	}
+	if strings.Contains(strings.ToLower(written), "0x") {
+		return strconv.FormatInt(v, 10)
+	}
+	return fmt.Sprintf("%#x", v)
+}
+
+// immediates returns the immediates of an instruction in the other base, like $255 = 0xff
+func (a *AssemblerFile) immediates(ins Instruction) string {
+	values := make([]string, 0, 1)
+	for _, operand := range ins.operands {
+		number := operand
+		if !a.ve {
+			if !strings.HasPrefix(operand, "$") {
+				continue
+			}
+			number = operand[1:]
+		}
+		if v, ok := parseint(number); ok {
+			if other := otherbase(number, v); other != "" {
+				values = append(values, operand+" = "+other)
+			}
+		}
+	}
+	return strings.Join(values, ", ")
+}
+
+// dataannotation returns the decoded value of a data line or label, or the immediates of an instruction
+func (a *AssemblerFile) dataannotation(line int) string {
+	if text, ok := a.dataannotations[line]; ok {
+		return text
+	}
+	if ins, ok := parseInstruction(a.filebuffer.GetLine(line)); ok {
+		return a.immediates(ins)
+	}
+	return ""
+}
+
+// describeconstants returns the decoded values of the constants an instruction refers to, one per line
+func (a *AssemblerFile) describeconstants(line int) []string {
+	ins, ok := parseInstruction(a.filebuffer.GetLine(line))
+	if !ok {
+		return nil
+	}
+	descs := make([]string, 0, 1)
+	for _, label := range a.constantrefs(ins) {
+		c := a.constants[label]
+		if text := c.decode(); text != "" {
+			descs = append(descs, fmt.Sprintf("%s (line %d): %s", label, c.line, text))
+		}
+	}
+	return descs
+}
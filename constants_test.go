@@ -0,0 +1,130 @@
+package main
+
+/*
+	tests of the decoded data values
+*/
+
+import "testing"
+
+func TestConstants(t *testing.T) {
+	a := testfile(t, `	.text
+	.globl	f
+	.type	f, @function
+f:
+	movsd	.LC0(%rip), %xmm0
+	mulpd	.LC2(%rip), %xmm1
+	movq	.LC5(%rip), %rax
+	addq	$255, %rax
+	andl	$0x10, %eax
+	ret
+	.size	f, .-f
+	.section	.rodata.str1.1,"aMS",@progbits,1
+.LC1:
+	.string	"hello\n"
+	.section	.rodata.cst8,"aM",@progbits,8
+	.align 8
+.LC0:
+	.long	0
+	.long	1074266112
+	.section	.rodata.cst16,"aM",@progbits,16
+	.align 16
+.LC2:
+	.long	0
+	.long	1072693248
+	.long	0
+	.long	1072693248
+	.section	.rodata.cst4,"aM",@progbits,4
+	.align 4
+.LC3:
+	.long	1065353216
+	.section	.rodata
+	.align 16
+.LC4:
+	.long	1
+	.long	-2
+	.long	3
+	.zero	4
+.LC5:
+	.long	0
+	.long	1074266112
+.LC6:
+	.byte	119
+	.byte	111
+	.byte	114
+	.byte	100
+.LC7:
+	.quad	.L3
+	.quad	.L4
+`)
+	tests := []struct {
+		line, annotation string
+	}{
+		{".LC1:", `string "hello\n"`},
+		{".LC0:", "double 3"},
+		{".LC2:", "double 2 x 1"},
+		{".LC3:", "float 1"},
+		{".LC4:", "int32 {1, -2, 3, 0}"},
+		{".LC5:", "int32 {0, 1074266112}"}, // loaded into a general purpose register, not a double
+		{".LC6:", `string "word"`},
+		{".LC7:", ""}, // addresses
+		{"$255, %rax", "$255 = 0xff"},
+		{"$0x10, %eax", "$0x10 = 16"},
+		{"1074266112", "0x40080000"},
+		{".byte 119", "'w'"},
+	}
+	for _, test := range tests {
+		if text := a.dataannotation(linewith(t, a, test.line)); text != test.annotation {
+			t.Errorf("annotation of %q = %q, want %q", test.line, text, test.annotation)
+		}
+	}
+}
+
+func TestPrintable(t *testing.T) {
+	tests := []struct {
+		data      string
+		printable bool
+	}{
+		{"hello\x00", true},
+		{"tab\tand newline\n", true},
+		{"caf\xc3\xa9", true},
+		{"\x00", false},
+		{"", false},
+		{"bell\x07", false},
+		{"\xff\xfe", false},
+	}
+	for _, test := range tests {
+		if printable([]byte(test.data)) != test.printable {
+			t.Errorf("printable(%q) = %v", test.data, !test.printable)
+		}
+	}
+}
+
+func TestFormatElements(t *testing.T) {
+	tests := []struct {
+		elements []string
+		text     string
+	}{
+		{[]string{"1"}, "1"},
+		{[]string{"2", "2", "2"}, "3 x 2"},
+		{[]string{"1", "2"}, "{1, 2}"},
+		{[]string{"1", "2", "3", "4", "5", "6", "7", "8", "9"}, "{1, 2, 3, 4, 5, 6, 7, 8, ...}"},
+	}
+	for _, test := range tests {
+		if text := formatelements(test.elements); text != test.text {
+			t.Errorf("formatelements(%v) = %q, want %q", test.elements, text, test.text)
+		}
+	}
+}
+
+func TestZero(t *testing.T) {
+	c := &Constant{width: -1}
+	for _, rest := range []string{"4", "-1", "0x10000", "n", "2, 1"} {
+		if !c.append(".zero", rest) {
+			t.Errorf(".zero %s is no data", rest)
+		}
+	}
+	// negative, too large and symbolic sizes are ignored
+	if len(c.data) != 6 {
+		t.Errorf("%d bytes, want 6", len(c.data))
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+/*
+	register definitions and uses
+
+	a simple def-use analysis on the basic blocks of a function,
+	good enough to answer "where did this register get its value"
+	as long as only one definition reaches the use.
+	register aliases are mapped to one canonical name, so %fp
+	and %s9 are the same register, as are %eax and %rax.
+*/
+
+import (
+	"strings"
+)
+
+var vealiases = map[string]string{
+	"%sl": "%s8", "%fp": "%s9", "%lr": "%s10", "%sp": "%s11", "%outer": "%s12",
+	"%tp": "%s14", "%got": "%s15", "%plt": "%s16", "%info": "%s17",
+}
+
+var x86gpr = map[string]string{
+	"al": "rax", "ah": "rax", "ax": "rax", "eax": "rax",
+	"bl": "rbx", "bh": "rbx", "bx": "rbx", "ebx": "rbx",
+	"cl": "rcx", "ch": "rcx", "cx": "rcx", "ecx": "rcx",
+	"dl": "rdx", "dh": "rdx", "dx": "rdx", "edx": "rdx",
+	"sil": "rsi", "si": "rsi", "esi": "rsi",
+	"dil": "rdi", "di": "rdi", "edi": "rdi",
+	"bpl": "rbp", "bp": "rbp", "ebp": "rbp",
+	"spl": "rsp", "sp": "rsp", "esp": "rsp",
+}
+
+// VE instructions which have no output register in first operand
+var venodef = map[string]bool{
+	"st": true, "stu": true, "stl": true, "st2b": true, "st1b": true,
+	"vst": true, "vstu": true, "vstl": true, "vst2d": true, "vstu2d": true, "vstl2d": true,
+	"vsc": true, "vscu": true, "vscl": true, "pfch": true, "pfchv": true,
+	"lvl": true, "shm": true, "scr": true, "lfr": true, "lpm": true, "lvix": true,
+	"svob": true, "fencei": true, "fencem": true, "fencec": true, "monc": true, "nop": true,
+}
+
+// canonicalregister maps register aliases and partial registers to one name
+func canonicalregister(reg string, ve bool) string {
+	if ve {
+		if c, ok := vealiases[reg]; ok {
+			return c
+		}
+		return reg
+	}
+	name := strings.TrimPrefix(reg, "%")
+	if c, ok := x86gpr[name]; ok {
+		return "%" + c
+	}
+	if len(name) > 2 && name[0] == 'r' && name[1] >= '0' && name[1] <= '9' {
+		return "%" + strings.TrimRight(name, "dwb")
+	}
+	for _, p := range []string{"xmm", "ymm", "zmm"} {
+		if strings.HasPrefix(name, p) {
+			return "%zmm" + name[3:]
+		}
+	}
+	return reg
+}
+
+// stripmask removes x86 avx512 masking like {%k1}{z} from an operand
+func stripmask(operand string) string {
+	if pos := strings.IndexByte(operand, '{'); pos > 0 {
+		return operand[:pos]
+	}
+	return operand
+}
+
+// definedregister returns the canonical register written by an instruction, or ""
+func definedregister(ins Instruction, ve bool) string {
+	if len(ins.operands) == 0 {
+		return ""
+	}
+	if ve {
+		base := ins.basemnemonic()
+		if venodef[base] || (isbranch(ins, true) && base != "bsic") {
+			return ""
+		}
+		op := ins.operands[0]
+		if len(op) > 1 && op[0] == '%' && strings.IndexByte(op, '(') == -1 {
+			return canonicalregister(op, true)
+		}
+		return ""
+	}
+	if readonlyx86(ins.mnemonic) || strings.HasPrefix(ins.mnemonic, "push") || ins.mnemonic[0] == 'j' {
+		return ""
+	}
+	op := stripmask(ins.operands[len(ins.operands)-1])
+	if len(op) > 1 && op[0] == '%' {
+		return canonicalregister(op, false)
+	}
+	return ""
+}
+
+// usedregisters returns the canonical registers read by an instruction
+func usedregisters(ins Instruction, ve bool) []string {
+	used := make([]string, 0, 4)
+	def := definedregister(ins, ve)
+	for i, op := range ins.operands {
+		if def != "" {
+			if ve && i == 0 {
+				continue
+			}
+			if !ve && i == len(ins.operands)-1 && x86overwrites(ins.mnemonic) {
+				op = stripmask(op)
+				if op[0] == '%' {
+					continue
+				}
+			}
+		}
+		for _, r := range registersin(op) {
+			used = append(used, canonicalregister(r, ve))
+		}
+	}
+	return used
+}
+
+// x86overwrites checks for x86 instructions which do not read their destination
+func x86overwrites(mnemonic string) bool {
+	for _, p := range []string{"mov", "vmov", "lea", "set", "cvt", "vcvt", "pop", "vbroadcast", "vpbroadcast"} {
+		if strings.HasPrefix(mnemonic, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// reachingdef searches the definition of reg reaching line on all paths.
+// returns the line or -1 if not found or if different definitions reach line
+func (a *AssemblerFile) reachingdef(blocks []Block, line int, reg string) int {
+	reg = canonicalregister(reg, a.ve)
+	bi := blockof(blocks, line)
+	if bi == -1 {
+		return -1
+	}
+	defs := make(map[int]bool)
+	entry := false // a path reaches function entry without definition
+	visited := make(map[int]bool)
+
+	var search func(bi, from int)
+	search = func(bi, from int) {
+		for l := from; l >= blocks[bi].start; l-- {
+			if ins, ok := parseInstruction(a.filebuffer.GetLine(l)); ok {
+				if definedregister(ins, a.ve) == reg {
+					defs[l] = true
+					return
+				}
+			}
+		}
+		if len(blocks[bi].preds) == 0 {
+			entry = true
+		}
+		for _, p := range blocks[bi].preds {
+			if !visited[p] {
+				visited[p] = true
+				search(p, blocks[p].end)
+			}
+		}
+	}
+	search(bi, line-1)
+
+	if len(defs) == 1 && !entry {
+		for l := range defs {
+			return l
+		}
+	}
+	return -1
+}
@@ -0,0 +1,925 @@
+package main
+
+/*
+	demangling of symbol names
+
+	C++ names are mangled following the Itanium C++ ABI, used by
+	gcc, clang and nc++ alike. the parser below covers names,
+	templates, function and member types, substitutions, operators,
+	constructors and destructors, local names, lambdas, abi tags
+	and clone suffixes like .isra.0. names it does not understand
+	(like most expressions in template arguments) are left raw.
+
+	Fortran module procedures are mangled as
+		__mod_MOD_sub   gfortran
+		mod_MP_sub_     nfort
+	both are shown as mod::sub.
+*/
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// rawnames disables demangling everywhere
+var rawnames bool
+
+var demanglecache = make(map[string]string)
+
+var resymboltoken = regexp.MustCompile(`[A-Za-z_.$][A-Za-z0-9_.$]*`)
+var regfortranmodule = regexp.MustCompile(`^__([a-z0-9_]+?)_MOD_([a-z0-9_]+)$`)
+var renfortmodule = regexp.MustCompile(`^([a-z0-9_]+?)_MP_([a-z0-9_]+)_$`)
+
+// symbolname returns the name of a symbol as it should be shown, demangled unless rawnames is set
+func symbolname(name string) string {
+	if rawnames {
+		return name
+	}
+	return demangle(name)
+}
+
+// ismangled tells if a name looks like a mangled C++ or Fortran name
+func ismangled(name string) bool {
+	return strings.HasPrefix(name, "_Z") || strings.HasPrefix(name, "_GLOBAL__sub_I_") ||
+		strings.Contains(name, "_MOD_") || strings.Contains(name, "_MP_")
+}
+
+// demangleline returns a line with all mangled symbols demangled, unless rawnames is set
+func demangleline(line string) string {
+	if rawnames || !(strings.Contains(line, "_Z") || strings.Contains(line, "_MOD_") || strings.Contains(line, "_MP_")) {
+		return line
+	}
+	return resymboltoken.ReplaceAllStringFunc(line, func(token string) string {
+		if ismangled(token) {
+			return demangle(token)
+		}
+		return token
+	})
+}
+
+// demangle returns the demangled form of a name, or the name if it is not mangled or not understood
+func demangle(name string) string {
+	if !ismangled(name) {
+		return name
+	}
+	if d, ok := demanglecache[name]; ok {
+		return d
+	}
+	d := name
+	if m := regfortranmodule.FindStringSubmatch(name); m != nil {
+		d = m[1] + "::" + m[2]
+	} else if m := renfortmodule.FindStringSubmatch(name); m != nil {
+		d = m[1] + "::" + m[2]
+	} else if strings.HasPrefix(name, "_GLOBAL__sub_I_") {
+		d = "global constructors keyed to " + demangle(name[len("_GLOBAL__sub_I_"):])
+	} else if strings.HasPrefix(name, "_Z") {
+		if s, ok := demanglecxx(name); ok {
+			d = s
+		}
+	}
+	demanglecache[name] = d
+	return d
+}
+
+// demanglefailure is raised by the parser for names it can not handle
+type demanglefailure struct{}
+
+// ctype is a parsed C++ type, printed around a declarator
+type ctype struct {
+	kind   int
+	name   string   // simple: the name, cv: the qualifiers, array: the dimension, member: the class
+	inner  *ctype   // element, pointee, qualified or member type, return type of functions
+	params []*ctype // function parameters
+	quals  string   // qualifiers of member functions
+}
+
+// kinds of types
+const (
+	typeSimple = iota
+	typePointer
+	typeLRef
+	typeRRef
+	typeCV
+	typeFunction
+	typeArray
+	typeMember
+	typePack
+)
+
+// cxxparser is the state of demangling one name
+type cxxparser struct {
+	s        string
+	pos      int
+	subs     []*ctype // substitution candidates
+	tmpl     []*ctype // template arguments of the function, for T_
+	lastname string   // last source name, for constructors and destructors
+	depth    int      // nesting of template arguments
+	settmpl  bool     // template arguments parsed are those of the function
+}
+
+// demanglecxx demangles an Itanium C++ ABI name
+func demanglecxx(name string) (result string, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, failed := r.(demanglefailure); !failed {
+				panic(r)
+			}
+			result, ok = "", false
+		}
+	}()
+	p := &cxxparser{s: name, pos: 2}
+	result = p.encoding()
+	// clone suffixes like .cold, .isra.0, .constprop.0.isra.0
+	for p.pos < len(p.s) && p.s[p.pos] == '.' {
+		start := p.pos
+		p.pos++
+		for p.pos < len(p.s) && (isalnum(p.s[p.pos]) || p.s[p.pos] == '_') {
+			p.pos++
+		}
+		for p.pos+1 < len(p.s) && p.s[p.pos] == '.' && isdigit(p.s[p.pos+1]) {
+			p.pos++
+			for p.pos < len(p.s) && isdigit(p.s[p.pos]) {
+				p.pos++
+			}
+		}
+		result += " [clone " + p.s[start:p.pos] + "]"
+	}
+	if p.pos != len(p.s) {
+		return "", false
+	}
+	return result, true
+}
+
+func isdigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isalnum(c byte) bool {
+	return isdigit(c) || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func (p *cxxparser) fail() {
+	panic(demanglefailure{})
+}
+
+func (p *cxxparser) peek() byte {
+	if p.pos < len(p.s) {
+		return p.s[p.pos]
+	}
+	return 0
+}
+
+func (p *cxxparser) peek2() string {
+	if p.pos+2 <= len(p.s) {
+		return p.s[p.pos : p.pos+2]
+	}
+	return ""
+}
+
+func (p *cxxparser) consume(prefix string) bool {
+	if strings.HasPrefix(p.s[p.pos:], prefix) {
+		p.pos += len(prefix)
+		return true
+	}
+	return false
+}
+
+func (p *cxxparser) expect(prefix string) {
+	if !p.consume(prefix) {
+		p.fail()
+	}
+}
+
+// number parses a non negative decimal number, n prefix for negative
+func (p *cxxparser) number() int {
+	negative := p.consume("n")
+	start := p.pos
+	for isdigit(p.peek()) {
+		p.pos++
+	}
+	if start == p.pos {
+		p.fail()
+	}
+	n, _ := strconv.Atoi(p.s[start:p.pos])
+	if negative {
+		return -n
+	}
+	return n
+}
+
+// seqid parses a base 36 sequence id of substitutions and template parameters
+func (p *cxxparser) seqid() int {
+	n := 0
+	for {
+		c := p.peek()
+		switch {
+		case isdigit(c):
+			n = n*36 + int(c-'0')
+		case c >= 'A' && c <= 'Z':
+			n = n*36 + int(c-'A') + 10
+		default:
+			return n
+		}
+		p.pos++
+	}
+}
+
+// encoding ::= <name> <bare-function-type> | <name> | <special-name>
+func (p *cxxparser) encoding() string {
+	if p.peek() == 'T' || p.peek2() == "GV" || p.peek2() == "GR" {
+		return p.specialname()
+	}
+	settmpl := p.settmpl
+	p.settmpl = true
+	name, templated, quals, noreturn := p.name()
+	p.settmpl = settmpl
+	if p.pos >= len(p.s) || p.peek() == 'E' || p.peek() == '.' {
+		return name
+	}
+	ret := ""
+	if templated && !noreturn {
+		ret = p.typestring(p.typ()) + " "
+	}
+	return ret + name + p.parameters() + quals
+}
+
+// parameters parses the parameter types of a function until the end of the encoding
+func (p *cxxparser) parameters() string {
+	params := make([]string, 0, 4)
+	for p.pos < len(p.s) && p.peek() != 'E' && p.peek() != '.' {
+		params = append(params, p.typestring(p.typ()))
+	}
+	if len(params) == 1 && params[0] == "void" {
+		params = params[:0]
+	}
+	return "(" + strings.Join(params, ", ") + ")"
+}
+
+// specialname parses vtables, typeinfo, guard variables and thunks
+func (p *cxxparser) specialname() string {
+	switch {
+	case p.consume("TV"):
+		return "vtable for " + p.typestring(p.typ())
+	case p.consume("TT"):
+		return "VTT for " + p.typestring(p.typ())
+	case p.consume("TI"):
+		return "typeinfo for " + p.typestring(p.typ())
+	case p.consume("TS"):
+		return "typeinfo name for " + p.typestring(p.typ())
+	case p.consume("TH"):
+		n, _, _, _ := p.name()
+		return "TLS init function for " + n
+	case p.consume("TW"):
+		n, _, _, _ := p.name()
+		return "TLS wrapper function for " + n
+	case p.consume("Th"):
+		p.number()
+		p.expect("_")
+		return "non-virtual thunk to " + p.encoding()
+	case p.consume("Tv"):
+		p.number()
+		p.expect("_")
+		p.number()
+		p.expect("_")
+		return "virtual thunk to " + p.encoding()
+	case p.consume("GV"):
+		n, _, _, _ := p.name()
+		return "guard variable for " + n
+	case p.consume("GR"):
+		n, _, _, _ := p.name()
+		if p.peek() != 'E' {
+			p.seqid()
+			p.expect("_")
+		}
+		return "reference temporary for " + n
+	}
+	p.fail()
+	return ""
+}
+
+// name parses a name, returns it, if it has template arguments, qualifiers of member functions
+// and if it has no return type (constructors, destructors and conversion operators)
+func (p *cxxparser) name() (string, bool, string, bool) {
+	switch p.peek() {
+	case 'N':
+		return p.nestedname()
+	case 'Z':
+		return p.localname()
+	}
+	var name string
+	var noreturn bool
+	if p.consume("St") {
+		var n string
+		n, noreturn = p.unqualifiedname()
+		name = "std::" + n
+	} else if p.peek() == 'S' {
+		name = p.typestring(p.substitution())
+		if p.peek() != 'I' {
+			// a substitution alone is not a name
+			p.fail()
+		}
+		return name + p.templateargs(), true, "", false
+	} else {
+		name, noreturn = p.unqualifiedname()
+	}
+	// the name with arguments is added by the caller if it is a type
+	if p.peek() == 'I' {
+		p.subs = append(p.subs, &ctype{name: name})
+		return withargs(name, p.templateargs()), true, "", noreturn
+	}
+	return name, false, "", noreturn
+}
+
+// nestedname ::= N [<CV-qualifiers>] [<ref-qualifier>] <prefix> <unqualified-name> E
+func (p *cxxparser) nestedname() (string, bool, string, bool) {
+	p.expect("N")
+	quals := p.cvqualifiers()
+	if p.consume("R") {
+		quals += " &"
+	} else if p.consume("O") {
+		quals += " &&"
+	}
+	name := ""
+	templated := false
+	noreturn := false
+	for !p.consume("E") {
+		var part string
+		if p.peek() != 'I' {
+			templated, noreturn = false, false
+		}
+		switch {
+		case p.peek() == 'S' && p.peek2() != "St":
+			part = p.typestring(p.substitution())
+			if name != "" {
+				p.fail()
+			}
+			if full, ok := stdfullnames[part]; ok {
+				part = full // std::string::size() reads as if string was a namespace
+			}
+			name = part
+			p.lastname = lastcomponent(part)
+			continue
+		case p.consume("St"):
+			name = "std"
+			continue
+		case p.peek() == 'T':
+			part = p.typestring(p.templateparam())
+			p.lastname = lastcomponent(part)
+		case p.peek() == 'I':
+			if name == "" {
+				p.fail()
+			}
+			name = withargs(name, p.templateargs())
+			templated = true
+			if p.peek() != 'E' {
+				p.subs = append(p.subs, &ctype{name: name})
+			}
+			continue
+		case p.peek() == 'M':
+			// initializer of a data member in a lambda, ignored
+			p.pos++
+			continue
+		default:
+			if name != "" {
+				p.lastname = lastcomponent(name)
+			}
+			part, noreturn = p.unqualifiedname()
+		}
+		if name != "" {
+			name += "::"
+		}
+		name += part
+		if p.peek() != 'E' {
+			p.subs = append(p.subs, &ctype{name: name})
+		}
+	}
+	return name, templated, quals, noreturn
+}
+
+// localname ::= Z <encoding> E <name> [<discriminator>] | Z <encoding> E s [<discriminator>]
+func (p *cxxparser) localname() (string, bool, string, bool) {
+	p.expect("Z")
+	outer := p.encoding()
+	p.expect("E")
+	if p.consume("s") {
+		p.discriminator()
+		return outer + "::string literal", false, "", false
+	}
+	if p.consume("d") {
+		// default argument
+		if p.peek() != '_' {
+			p.number()
+		}
+		p.expect("_")
+	}
+	name, templated, quals, noreturn := p.name()
+	p.discriminator()
+	return outer + "::" + name, templated, quals, noreturn
+}
+
+// discriminator ::= _ <digit> | __ <number> _
+func (p *cxxparser) discriminator() {
+	if p.consume("__") {
+		p.number()
+		p.expect("_")
+	} else if p.peek() == '_' && p.pos+1 < len(p.s) && isdigit(p.s[p.pos+1]) {
+		p.pos += 2
+	}
+}
+
+// unqualifiedname parses operators, constructors, destructors, source names and unnamed types
+func (p *cxxparser) unqualifiedname() (string, bool) {
+	var name string
+	noreturn := false
+	c := p.peek()
+	switch {
+	case isdigit(c):
+		name = p.sourcename()
+		p.lastname = name
+	case c == 'C' || c == 'D' && (p.peek2() == "D0" || p.peek2() == "D1" || p.peek2() == "D2" || p.peek2() == "D4" || p.peek2() == "D5"):
+		name = p.ctordtorname()
+		noreturn = true
+	case p.consume("Ut"):
+		n := 1
+		if p.peek() != '_' {
+			n = p.number() + 2
+		}
+		p.expect("_")
+		name = "{unnamed type#" + strconv.Itoa(n) + "}"
+	case p.consume("Ul"):
+		params := make([]string, 0, 4)
+		for !p.consume("E") {
+			params = append(params, p.typestring(p.typ()))
+		}
+		if len(params) == 1 && params[0] == "void" {
+			params = params[:0]
+		}
+		n := 1
+		if p.peek() != '_' {
+			n = p.number() + 2
+		}
+		p.expect("_")
+		name = "{lambda(" + strings.Join(params, ", ") + ")#" + strconv.Itoa(n) + "}"
+	case p.consume("DC"):
+		parts := make([]string, 0, 4)
+		for !p.consume("E") {
+			parts = append(parts, p.sourcename())
+		}
+		name = "[" + strings.Join(parts, ", ") + "]"
+	case c == 'L':
+		// internal linkage
+		p.pos++
+		return p.unqualifiedname()
+	default:
+		name, noreturn = p.operatorname()
+	}
+	for p.consume("B") {
+		name += "[abi:" + p.sourcename() + "]"
+	}
+	return name, noreturn
+}
+
+// sourcename ::= <number> <identifier>
+func (p *cxxparser) sourcename() string {
+	n := p.number()
+	if n <= 0 || p.pos+n > len(p.s) {
+		p.fail()
+	}
+	name := p.s[p.pos : p.pos+n]
+	p.pos += n
+	if strings.HasPrefix(name, "_GLOBAL__N") {
+		return "(anonymous namespace)"
+	}
+	return name
+}
+
+// ctordtorname parses constructors and destructors, named after the class
+func (p *cxxparser) ctordtorname() string {
+	if p.lastname == "" {
+		p.fail()
+	}
+	switch {
+	case p.consume("CI1"), p.consume("CI2"):
+		p.typ()
+		return p.lastname
+	case p.consume("C1"), p.consume("C2"), p.consume("C3"), p.consume("C4"), p.consume("C5"):
+		return p.lastname
+	case p.consume("D0"), p.consume("D1"), p.consume("D2"), p.consume("D4"), p.consume("D5"):
+		return "~" + p.lastname
+	}
+	p.fail()
+	return ""
+}
+
+// operators by their two letter code
+var cxxoperators = map[string]string{
+	"nw": "new", "na": "new[]", "dl": "delete", "da": "delete[]", "aw": "co_await",
+	"ps": "+", "ng": "-", "ad": "&", "de": "*", "co": "~",
+	"pl": "+", "mi": "-", "ml": "*", "dv": "/", "rm": "%", "an": "&", "or": "|", "eo": "^",
+	"aS": "=", "pL": "+=", "mI": "-=", "mL": "*=", "dV": "/=", "rM": "%=", "aN": "&=", "oR": "|=", "eO": "^=",
+	"ls": "<<", "rs": ">>", "lS": "<<=", "rS": ">>=", "eq": "==", "ne": "!=", "lt": "<", "gt": ">",
+	"le": "<=", "ge": ">=", "ss": "<=>", "nt": "!", "aa": "&&", "oo": "||", "pp": "++", "mm": "--",
+	"cm": ",", "pm": "->*", "pt": "->", "cl": "()", "ix": "[]", "qu": "?",
+}
+
+// operatorname parses an operator, returns if it is a conversion operator
+func (p *cxxparser) operatorname() (string, bool) {
+	code := p.peek2()
+	if op, ok := cxxoperators[code]; ok {
+		p.pos += 2
+		if op[0] >= 'a' && op[0] <= 'z' {
+			return "operator " + op, false
+		}
+		return "operator" + op, false
+	}
+	switch {
+	case p.consume("cv"):
+		return "operator " + p.typestring(p.typ()), true
+	case p.consume("li"):
+		return "operator\"\" " + p.sourcename(), false
+	case p.peek() == 'v' && p.pos+1 < len(p.s) && isdigit(p.s[p.pos+1]):
+		p.pos += 2
+		return "operator " + p.sourcename(), false
+	}
+	p.fail()
+	return "", false
+}
+
+// cvqualifiers parses r V K, returns them as printed after a type
+func (p *cxxparser) cvqualifiers() string {
+	quals := ""
+	if p.consume("r") {
+		quals = " restrict"
+	}
+	if p.consume("V") {
+		quals = " volatile" + quals
+	}
+	if p.consume("K") {
+		quals = " const" + quals
+	}
+	return quals
+}
+
+// builtin types by their code
+var cxxbuiltins = map[byte]string{
+	'v': "void", 'w': "wchar_t", 'b': "bool", 'c': "char", 'a': "signed char", 'h': "unsigned char",
+	's': "short", 't': "unsigned short", 'i': "int", 'j': "unsigned int", 'l': "long", 'm': "unsigned long",
+	'x': "long long", 'y': "unsigned long long", 'n': "__int128", 'o': "unsigned __int128",
+	'f': "float", 'd': "double", 'e': "long double", 'g': "__float128", 'z': "...",
+}
+
+// builtin types with D prefix
+var cxxdbuiltins = map[byte]string{
+	'd': "decimal64", 'e': "decimal128", 'f': "decimal32", 'h': "half", 'i': "char32_t",
+	's': "char16_t", 'u': "char8_t", 'a': "auto", 'c': "decltype(auto)", 'n': "decltype(nullptr)",
+}
+
+// typ parses a type
+func (p *cxxparser) typ() *ctype {
+	c := p.peek()
+	if b, ok := cxxbuiltins[c]; ok {
+		p.pos++
+		return &ctype{name: b}
+	}
+	var t *ctype
+	switch c {
+	case 'r', 'V', 'K':
+		quals := p.cvqualifiers()
+		t = &ctype{kind: typeCV, name: quals, inner: p.typ()}
+	case 'P':
+		p.pos++
+		t = &ctype{kind: typePointer, inner: p.typ()}
+	case 'R':
+		p.pos++
+		t = &ctype{kind: typeLRef, inner: p.typ()}
+	case 'O':
+		p.pos++
+		t = &ctype{kind: typeRRef, inner: p.typ()}
+	case 'F':
+		t = p.functiontype()
+	case 'A':
+		p.pos++
+		dim := ""
+		if p.peek() != '_' {
+			dim = strconv.Itoa(p.number())
+		}
+		p.expect("_")
+		t = &ctype{kind: typeArray, name: dim, inner: p.typ()}
+	case 'M':
+		p.pos++
+		class := p.typestring(p.typ())
+		t = &ctype{kind: typeMember, name: class, inner: p.typ()}
+	case 'T':
+		t = p.templateparam()
+		if p.peek() == 'I' {
+			p.subs = append(p.subs, t)
+			t = &ctype{name: p.typestring(t) + p.templateargs()}
+		}
+	case 'S':
+		if p.peek2() == "St" {
+			n, _, _, _ := p.name()
+			t = &ctype{name: n}
+			break
+		}
+		t = p.substitution()
+		if p.peek() != 'I' {
+			return t // substitutions are not added again
+		}
+		t = &ctype{name: p.typestring(t) + p.templateargs()}
+	case 'U':
+		p.pos++
+		p.sourcename() // vendor qualifier, ignored
+		return p.typ()
+	case 'u':
+		p.pos++
+		return &ctype{name: p.sourcename()}
+	case 'D':
+		if p.pos+1 < len(p.s) {
+			if b, ok := cxxdbuiltins[p.s[p.pos+1]]; ok {
+				p.pos += 2
+				return &ctype{name: b}
+			}
+		}
+		switch {
+		case p.consume("Dp"):
+			t = &ctype{kind: typePack, inner: p.typ()}
+		case p.consume("DF"):
+			n := p.number()
+			p.expect("_")
+			return &ctype{name: "_Float" + strconv.Itoa(n)}
+		case p.consume("Dv"):
+			n := p.number()
+			p.expect("_")
+			inner := p.typ()
+			t = &ctype{name: p.typestring(inner) + " __vector(" + strconv.Itoa(n) + ")"}
+		case p.peek2() == "Dx", p.peek2() == "Do", p.peek2() == "Dw":
+			// exception specifications of function types
+			t = p.functiontype()
+		default:
+			p.fail()
+		}
+	case 'N', 'Z':
+		n, _, _, _ := p.name()
+		t = &ctype{name: n}
+	default:
+		if !isdigit(c) {
+			p.fail()
+		}
+		n, _, _, _ := p.name()
+		t = &ctype{name: n}
+	}
+	p.subs = append(p.subs, t)
+	return t
+}
+
+// functiontype ::= [<exception-spec>] [Dx] F [Y] <return type> <parameter types> [<ref-qualifier>] E
+func (p *cxxparser) functiontype() *ctype {
+	p.consume("Do")
+	p.consume("Dx")
+	p.expect("F")
+	p.consume("Y")
+	t := &ctype{kind: typeFunction, inner: p.typ()}
+	for !p.consume("E") {
+		if p.consume("RE") {
+			t.quals = " &"
+			break
+		}
+		if p.consume("OE") {
+			t.quals = " &&"
+			break
+		}
+		param := p.typ()
+		if param.kind == typeSimple && param.name == "void" && len(t.params) == 0 && p.peek() == 'E' {
+			continue
+		}
+		t.params = append(t.params, param)
+	}
+	return t
+}
+
+// substitution ::= S_ | S <seq-id> _ | Sa | Sb | Ss | Si | So | Sd
+func (p *cxxparser) substitution() *ctype {
+	p.expect("S")
+	switch p.peek() {
+	case 'a':
+		p.pos++
+		return &ctype{name: "std::allocator"}
+	case 'b':
+		p.pos++
+		return &ctype{name: "std::basic_string"}
+	case 's':
+		p.pos++
+		return &ctype{name: "std::string"}
+	case 'i':
+		p.pos++
+		return &ctype{name: "std::istream"}
+	case 'o':
+		p.pos++
+		return &ctype{name: "std::ostream"}
+	case 'd':
+		p.pos++
+		return &ctype{name: "std::iostream"}
+	}
+	n := 0
+	if p.peek() != '_' {
+		n = p.seqid() + 1
+	}
+	p.expect("_")
+	if n >= len(p.subs) {
+		p.fail()
+	}
+	return p.subs[n]
+}
+
+// templateparam ::= T_ | T <number> _
+func (p *cxxparser) templateparam() *ctype {
+	p.expect("T")
+	n := 0
+	if p.peek() != '_' {
+		n = p.number() + 1
+	}
+	p.expect("_")
+	if n >= len(p.tmpl) {
+		p.fail()
+	}
+	return p.tmpl[n]
+}
+
+// templateargs ::= I <template-arg>+ E
+func (p *cxxparser) templateargs() string {
+	p.expect("I")
+	p.depth++
+	args := make([]*ctype, 0, 4)
+	for !p.consume("E") {
+		args = append(args, p.templatearg())
+	}
+	p.depth--
+	if p.depth == 0 && p.settmpl {
+		p.tmpl = args
+	}
+	parts := make([]string, len(args))
+	for i, a := range args {
+		parts[i] = p.typestring(a)
+	}
+	s := "<" + strings.Join(parts, ", ")
+	if strings.HasSuffix(s, ">") {
+		s += " "
+	}
+	return s + ">"
+}
+
+// templatearg ::= <type> | L <literal> E | J <template-arg>* E | X <expression> E
+func (p *cxxparser) templatearg() *ctype {
+	switch p.peek() {
+	case 'L':
+		return &ctype{name: p.literal()}
+	case 'J':
+		p.pos++
+		args := make([]*ctype, 0, 4)
+		for !p.consume("E") {
+			args = append(args, p.templatearg())
+		}
+		if len(args) == 1 {
+			return args[0]
+		}
+		parts := make([]string, len(args))
+		for i, a := range args {
+			parts[i] = p.typestring(a)
+		}
+		return &ctype{name: strings.Join(parts, ", ")}
+	case 'X':
+		p.fail() // expressions are not supported
+	}
+	return p.typ()
+}
+
+// literal ::= L <type> <value> E | L _Z <encoding> E
+func (p *cxxparser) literal() string {
+	p.expect("L")
+	if p.consume("_Z") {
+		s := p.encoding()
+		p.expect("E")
+		return s
+	}
+	t := p.typestring(p.typ())
+	start := p.pos
+	for p.peek() != 'E' && p.pos < len(p.s) {
+		p.pos++
+	}
+	value := p.s[start:p.pos]
+	p.expect("E")
+	if strings.HasPrefix(value, "n") {
+		value = "-" + value[1:]
+	}
+	switch t {
+	case "bool":
+		if value == "0" {
+			return "false"
+		}
+		return "true"
+	case "int":
+		return value
+	case "unsigned int":
+		return value + "u"
+	case "long":
+		return value + "l"
+	case "unsigned long":
+		return value + "ul"
+	case "decltype(nullptr)":
+		return "nullptr"
+	}
+	return "(" + t + ")" + value
+}
+
+// typestring prints a type
+func (p *cxxparser) typestring(t *ctype) string {
+	return declarator(t, "")
+}
+
+// declarator prints a type around an inner declarator like "*" or "(*)"
+func declarator(t *ctype, inner string) string {
+	switch t.kind {
+	case typePointer:
+		return declarator(t.inner, "*"+inner)
+	case typeLRef:
+		if t.inner.kind == typeLRef || t.inner.kind == typeRRef {
+			return declarator(t.inner.inner, "&"+inner) // reference collapsing
+		}
+		return declarator(t.inner, "&"+inner)
+	case typeRRef:
+		if t.inner.kind == typeLRef || t.inner.kind == typeRRef {
+			return declarator(t.inner, inner)
+		}
+		return declarator(t.inner, "&&"+inner)
+	case typeCV:
+		if t.inner.kind == typeFunction {
+			return declarator(t.inner, inner) + t.name
+		}
+		return declarator(t.inner, t.name+inner)
+	case typeMember:
+		return declarator(t.inner, t.name+"::*"+inner)
+	case typePack:
+		// packs are expanded by the template arguments
+		return declarator(t.inner, inner)
+	case typeFunction:
+		params := make([]string, len(t.params))
+		for i, param := range t.params {
+			params[i] = declarator(param, "")
+		}
+		s := declarator(t.inner, "") + " "
+		if inner != "" {
+			s += "(" + strings.TrimLeft(inner, " ") + ")"
+		}
+		return s + "(" + strings.Join(params, ", ") + ")" + t.quals
+	case typeArray:
+		s := declarator(t.inner, "") + " "
+		if inner != "" {
+			s += "(" + strings.TrimLeft(inner, " ") + ") "
+		}
+		return s + "[" + t.name + "]"
+	}
+	if inner == "" {
+		return t.name
+	}
+	if inner[0] == '*' || inner[0] == '&' || inner[0] == ' ' {
+		return t.name + inner
+	}
+	return t.name + " " + inner
+}
+
+// withargs appends template arguments to a name, operator< gets a space in between
+func withargs(name, args string) string {
+	if strings.HasSuffix(name, "<") {
+		return name + " " + args
+	}
+	return name + args
+}
+
+// lastcomponent returns the last component of a qualified name without template arguments
+func lastcomponent(name string) string {
+	depth, start := 0, 0
+	for i := 0; i < len(name); i++ {
+		switch {
+		case name[i] == '<':
+			depth++
+		case name[i] == '>':
+			depth--
+		case depth == 0 && strings.HasPrefix(name[i:], "::"):
+			start = i + 2
+		}
+	}
+	name = name[start:]
+	if i := strings.IndexAny(name, "<["); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// full names of the std abbreviations, used when they are the prefix of a nested name
+var stdfullnames = map[string]string{
+	"std::string":   "std::basic_string<char, std::char_traits<char>, std::allocator<char> >",
+	"std::istream":  "std::basic_istream<char, std::char_traits<char> >",
+	"std::ostream":  "std::basic_ostream<char, std::char_traits<char> >",
+	"std::iostream": "std::basic_iostream<char, std::char_traits<char> >",
+}
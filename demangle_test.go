@@ -0,0 +1,62 @@
+package main
+
+/*
+	tests of the demangler, C++ names as shown by c++filt
+*/
+
+import "testing"
+
+func TestDemangle(t *testing.T) {
+	tests := []struct {
+		mangled, name string
+	}{
+		// plain names are left alone
+		{"main", "main"},
+		{".LC0", ".LC0"},
+		// functions, nested names, constructors, destructors, operators
+		{"_Z3fooi", "foo(int)"},
+		{"_Z3barPKcz", "bar(char const*, ...)"},
+		{"_ZN2ns3fooEv", "ns::foo()"},
+		{"_ZN2ns5Outer5InnerC2Ev", "ns::Outer::Inner::Inner()"},
+		{"_ZN2ns5Outer5InnerD1Ev", "ns::Outer::Inner::~Inner()"},
+		{"_ZNK3Vec4sizeEv", "Vec::size() const"},
+		{"_ZN3VecixEm", "Vec::operator[](unsigned long)"},
+		{"_ZN1AaSERKS_", "A::operator=(A const&)"},
+		{"_ZL6helperv", "helper()"},
+		{"_ZSt4cout", "std::cout"},
+		{"_ZNSt8ios_base4InitC1Ev", "std::ios_base::Init::Init()"},
+		// function pointers and arrays
+		{"_Z3fooPFviE", "foo(void (*)(int))"},
+		{"_Z6kernelPA3_d", "kernel(double (*) [3])"},
+		{"_Z3fooRA10_i", "foo(int (&) [10])"},
+		// templates
+		{"_Z5applyIdEvPT_S1_i", "void apply<double>(double*, double*, int)"},
+		{"_Z3maxIiET_S0_S0_", "int max<int>(int, int)"},
+		{"_Z4axpyILi4EEvPdPKdd", "void axpy<4>(double*, double const*, double)"},
+		{"_ZNKSt5ctypeIcE8do_widenEc", "std::ctype<char>::do_widen(char) const"},
+		{"_ZN9__gnu_cxx13new_allocatorIcED2Ev", "__gnu_cxx::new_allocator<char>::~new_allocator()"},
+		// substitutions
+		{"_ZplRK3VecS1_", "operator+(Vec const&, Vec const&)"},
+		{"_Z1fSt6vectorIiSaIiEE", "f(std::vector<int, std::allocator<int> >)"},
+		{"_ZNSt6vectorIdSaIdEE9push_backERKd", "std::vector<double, std::allocator<double> >::push_back(double const&)"},
+		{"_ZStlsISt11char_traitsIcEERSt13basic_ostreamIcT_ES5_PKc",
+			"std::basic_ostream<char, std::char_traits<char> >& std::operator<< <std::char_traits<char> >(std::basic_ostream<char, std::char_traits<char> >&, char const*)"},
+		{"_ZNSt6vectorIiSaIiEE17_M_realloc_insertIJRKiEEEvN9__gnu_cxx17__normal_iteratorIPiS1_EEDpOT_",
+			"void std::vector<int, std::allocator<int> >::_M_realloc_insert<int const&>(__gnu_cxx::__normal_iterator<int*, std::vector<int, std::allocator<int> > >, int const&)"},
+		// lambdas
+		{"_ZZ4mainENKUlvE_clEv", "main::{lambda()#1}::operator()() const"},
+		{"_ZZ4mainENKUliE0_clEi", "main::{lambda(int)#2}::operator()(int) const"},
+		{"_ZZ3runvENKUlRKdE_clES0_", "run()::{lambda(double const&)#1}::operator()(double const&) const"},
+		// clone suffixes
+		{"_ZN5Space4funcEv.isra.0", "Space::func() [clone .isra.0]"},
+		// Fortran module procedures of gfortran and nfort
+		{"__solver_MOD_step", "solver::step"},
+		{"__solver_MOD_init_grid", "solver::init_grid"},
+		{"solver_MP_step_", "solver::step"},
+	}
+	for _, test := range tests {
+		if name := demangle(test.mangled); name != test.name {
+			t.Errorf("demangle(%q) = %q, want %q", test.mangled, name, test.name)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package main
+
+/*
+	assembly diff between two builds
+
+		veass diff old.s new.s [symbol ...]
+
+	functions are matched by their global symbol, the instructions
+	of each pair of functions are compared after normalization:
+	register numbers and label names are replaced by placeholders,
+	so register renaming and label renumbering do not show up.
+	the diff is printed side by side, followed by a summary of
+	added and removed instructions by class.
+
+	the diff is the shortest edit script of Myers' algorithm, in the
+	linear space variant dividing at the middle snake.
+*/
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+const diffcolumn = 64 // width of one side of the diff
+
+var relocallabel = regexp.MustCompile(`\.L[A-Za-z_]*[0-9][0-9_.]*`)
+
+// diffline is an instruction with its normalized form
+type diffline struct {
+	line       int
+	text       string
+	normalized string
+	class      int
+}
+
+// normalizeregister replaces a register by its class
+func normalizeregister(reg string, ve bool) string {
+	if ve {
+		if _, ok := vealiases[reg]; ok {
+			return reg
+		}
+		return strings.TrimRight(reg, "0123456789")
+	}
+	switch c := canonicalregister(reg, false); c {
+	case "%rsp", "%rbp", "%rip":
+		return c
+	default:
+		if strings.HasPrefix(reg, "%xmm") || strings.HasPrefix(reg, "%ymm") || strings.HasPrefix(reg, "%zmm") || strings.HasPrefix(reg, "%k") {
+			return strings.TrimRight(reg, "0123456789")
+		}
+		if strings.HasPrefix(c, "%r") {
+			return "%r"
+		}
+		return reg
+	}
+}
+
+// normalize returns an instruction without register numbers and label names
+func normalize(ins Instruction, ve bool) string {
+	ops := make([]string, len(ins.operands))
+	for i, op := range ins.operands {
+		op = relocallabel.ReplaceAllString(op, ".L")
+		ops[i] = reregister.ReplaceAllStringFunc(op, func(r string) string { return normalizeregister(r, ve) })
+	}
+	return ins.mnemonic + " " + strings.Join(ops, ",")
+}
+
+// difflines returns the instructions of a function
+func (a *AssemblerFile) difflines(fi int) []diffline {
+	f := a.functions[fi]
+	lines := make([]diffline, 0, f.instructions)
+	for l := f.start; l <= f.end; l++ {
+		text := a.filebuffer.GetLine(l)
+		if ins, ok := parseInstruction(text); ok {
+			lines = append(lines, diffline{l, strings.Join(strings.Fields(demangleline(text)), " "), normalize(ins, a.ve), a.classify(ins)})
+		}
+	}
+	return lines
+}
+
+// edit operations
+const (
+	editSame = iota
+	editRemove
+	editAdd
+)
+
+// edit is one step of an edit script, indices into old and new lines
+type edit struct {
+	op       int
+	old, new int
+}
+
+// myers computes the shortest edit script from a to b
+func myers(a, b []diffline) []edit {
+	return diffrange(a, b, 0, 0, make([]edit, 0, len(a)+len(b)))
+}
+
+// diffrange appends the edit script of a and b, which start at x0 and y0 of the functions,
+// divided at the middle snake, so memory grows with the length and not with the differences
+func diffrange(a, b []diffline, x0, y0 int, edits []edit) []edit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix].normalized == b[prefix].normalized {
+		edits = append(edits, edit{editSame, x0 + prefix, y0 + prefix})
+		prefix++
+	}
+	a, b, x0, y0 = a[prefix:], b[prefix:], x0+prefix, y0+prefix
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix].normalized == b[len(b)-1-suffix].normalized {
+		suffix++
+	}
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+	switch {
+	case len(a) == 0:
+		for y := range b {
+			edits = append(edits, edit{editAdd, x0, y0 + y})
+		}
+	case len(b) == 0:
+		for x := range a {
+			edits = append(edits, edit{editRemove, x0 + x, y0})
+		}
+	default:
+		x, y, ok := middlesnake(a, b)
+		if !ok {
+			// nothing in common
+			for x := range a {
+				edits = append(edits, edit{editRemove, x0 + x, y0})
+			}
+			for y := range b {
+				edits = append(edits, edit{editAdd, x0 + len(a), y0 + y})
+			}
+			break
+		}
+		edits = diffrange(a[:x], b[:y], x0, y0, edits)
+		edits = diffrange(a[x:], b[y:], x0+x, y0+y, edits)
+	}
+
+	for i := 0; i < suffix; i++ {
+		edits = append(edits, edit{editSame, x0 + len(a) + i, y0 + len(b) + i})
+	}
+	return edits
+}
+
+// middlesnake searches the shortest edit script forward from the start and backward from the end
+// at the same time, and returns the point where both meet, which is on a shortest edit script.
+// a and b differ in their first and last lines, false means they have nothing in common
+func middlesnake(a, b []diffline) (int, int, bool) {
+	n, m := len(a), len(b)
+	maxd := (n + m + 1) / 2
+	offset := maxd
+	forward := make([]int, 2*maxd+2)  // furthest x on diagonal k = x-y searching forward
+	backward := make([]int, 2*maxd+2) // furthest x on diagonal k counted from the end
+	for i := range forward {
+		forward[i] = -1
+		backward[i] = -1
+	}
+	forward[offset+1] = 0
+	backward[offset+1] = 0
+	delta := n - m
+	odd := delta%2 != 0
+	// diagonals which left the edit graph are skipped
+	fstart, fend, bstart, bend := 0, 0, 0, 0
+
+	for d := 0; d < maxd; d++ {
+		for k := -d + fstart; k <= d-fend; k += 2 {
+			var x int
+			if k == -d || k != d && forward[offset+k-1] < forward[offset+k+1] {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x].normalized == b[y].normalized {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+			switch {
+			case x > n:
+				fend += 2
+			case y > m:
+				fstart += 2
+			case odd:
+				if bk := offset + delta - k; bk >= 0 && bk < len(backward) && backward[bk] != -1 && x >= n-backward[bk] {
+					return x, y, true
+				}
+			}
+		}
+		for k := -d + bstart; k <= d-bend; k += 2 {
+			var x int
+			if k == -d || k != d && backward[offset+k-1] < backward[offset+k+1] {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[n-x-1].normalized == b[m-y-1].normalized {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+			switch {
+			case x > n:
+				bend += 2
+			case y > m:
+				bstart += 2
+			case !odd:
+				if fk := offset + delta - k; fk >= 0 && fk < len(forward) && forward[fk] != -1 && forward[fk] >= n-x {
+					return forward[fk], forward[fk] - (fk - offset), true
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// column cuts or pads text to the width of a diff column
+func column(text string) string {
+	if len(text) > diffcolumn {
+		return text[:diffcolumn-1] + ">"
+	}
+	return fmt.Sprintf("%-*s", diffcolumn, text)
+}
+
+// classsummary formats counts by class like "vector 3, gather 1"
+func classsummary(counts [nrclasses]int) string {
+	parts := make([]string, 0, nrclasses)
+	for c := 0; c < nrclasses; c++ {
+		if counts[c] > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", classnames[c], counts[c]))
+		}
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// difffunction prints the side by side diff of two functions, adds the changes to added and removed
+func difffunction(old, new []diffline, added, removed *[nrclasses]int) {
+	edits := myers(old, new)
+	for i := 0; i < len(edits); {
+		e := edits[i]
+		if e.op == editSame {
+			fmt.Printf("%6d %s   %6d %s\n", old[e.old].line, column(old[e.old].text), new[e.new].line, new[e.new].text)
+			i++
+			continue
+		}
+		// pair a run of removes with the following run of adds
+		removes := make([]int, 0, 4)
+		adds := make([]int, 0, 4)
+		for ; i < len(edits) && edits[i].op == editRemove; i++ {
+			removes = append(removes, edits[i].old)
+			removed[old[edits[i].old].class]++
+		}
+		for ; i < len(edits) && edits[i].op == editAdd; i++ {
+			adds = append(adds, edits[i].new)
+			added[new[edits[i].new].class]++
+		}
+		for j := 0; j < maxi(len(removes), len(adds)); j++ {
+			left := fmt.Sprintf("%6s %s", "", column(""))
+			right := ""
+			marker := "|"
+			if j < len(removes) {
+				left = fmt.Sprintf("%6d %s", old[removes[j]].line, column(old[removes[j]].text))
+			} else {
+				marker = ">"
+			}
+			if j < len(adds) {
+				right = fmt.Sprintf("%6d %s", new[adds[j]].line, new[adds[j]].text)
+			} else {
+				marker = "<"
+			}
+			fmt.Printf("%s %s %s\n", left, marker, right)
+		}
+	}
+}
+
+// rundiff implements the diff command, returns the exit code
+func rundiff(args []string) int {
+	if len(args) < 2 {
+		fmt.Println("usage: veass diff <old.s> <new.s> [symbol ...]")
+		return 2
+	}
+	progress = ioutil.Discard
+
+	oldfile, err := NewAssemblerFile(args[0])
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	newfile, err := NewAssemblerFile(args[1])
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	selected := make(map[string]bool)
+	for _, s := range args[2:] {
+		selected[s] = true
+	}
+
+	var totaladded, totalremoved [nrclasses]int
+	for fi, f := range oldfile.functions {
+		if len(selected) > 0 && !selected[f.name] {
+			continue
+		}
+		nfi := newfile.functionbyname(f.name)
+		if nfi == -1 {
+			fmt.Printf("=== %s: only in %s\n\n", symbolname(f.name), args[0])
+			continue
+		}
+		var added, removed [nrclasses]int
+		fmt.Printf("=== %s: %d -> %d instructions\n", symbolname(f.name), f.instructions, newfile.functions[nfi].instructions)
+		difffunction(oldfile.difflines(fi), newfile.difflines(nfi), &added, &removed)
+		fmt.Printf("    added:   %s\n    removed: %s\n\n", classsummary(added), classsummary(removed))
+		for c := 0; c < nrclasses; c++ {
+			totaladded[c] += added[c]
+			totalremoved[c] += removed[c]
+		}
+	}
+	for _, f := range newfile.functions {
+		if (len(selected) == 0 || selected[f.name]) && oldfile.functionbyname(f.name) == -1 {
+			fmt.Printf("=== %s: only in %s\n\n", symbolname(f.name), args[1])
+		}
+	}
+	fmt.Printf("total added:   %s\ntotal removed: %s\n", classsummary(totaladded), classsummary(totalremoved))
+	return 0
+}
@@ -0,0 +1,101 @@
+package main
+
+/*
+	tests of the normalization and the edit script of the diff command
+*/
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		ve    bool
+		equal bool
+	}{
+		{"\taddsd\t%xmm1, %xmm0", "\taddsd\t%xmm5, %xmm2", false, true},
+		{"\tvaddpd\t(%rdi,%rax), %ymm1, %ymm0", "\tvaddpd\t(%rsi,%rdx), %ymm3, %ymm4", false, true},
+		{"\tmovl\t%eax, %r8d", "\tmovl\t%ecx, %r10d", false, true},
+		{"\tjne\t.L3", "\tjne\t.L17", false, true},
+		{"\tmovsd\t.LC0(%rip), %xmm0", "\tmovsd\t.LC12(%rip), %xmm1", false, true},
+		{"\tmovq\t-8(%rbp), %rax", "\tmovq\t-8(%rsp), %rax", false, false},
+		{"\taddsd\t%xmm1, %xmm0", "\taddpd\t%xmm1, %xmm0", false, false},
+		{"\taddsd\t%xmm1, %xmm0", "\tvaddsd\t%xmm1, %xmm0, %xmm0", false, false},
+		{"\tmovq\t8(%rax), %rbx", "\tmovq\t16(%rax), %rbx", false, false},
+		{"\tvfadd.d\t%v2,%v1,%v0", "\tvfadd.d\t%v5,%v3,%v1", true, true},
+		{"\tbrlt.l\t%s2,%s0,.L1.0", "\tbrlt.l\t%s7,%s3,.L5.2", true, true},
+		{"\tld\t%s1,-8(,%fp)", "\tld\t%s1,-8(,%sp)", true, false},
+	}
+	for _, test := range tests {
+		a, _ := parseInstruction(test.a)
+		b, _ := parseInstruction(test.b)
+		if na, nb := normalize(a, test.ve), normalize(b, test.ve); (na == nb) != test.equal {
+			t.Errorf("normalized %q and %q: %q, %q, equal should be %v", test.a, test.b, na, nb, test.equal)
+		}
+	}
+}
+
+// difftestlines returns instructions for the diff, one per letter of text
+func difftestlines(text string) []diffline {
+	lines := make([]diffline, len(text))
+	for i, c := range text {
+		lines[i] = diffline{line: i + 1, text: string(c), normalized: string(c)}
+	}
+	return lines
+}
+
+func TestMyers(t *testing.T) {
+	tests := []struct {
+		a, b    string
+		changes int // removed and added lines
+	}{
+		{"", "", 0},
+		{"abc", "abc", 0},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"abc", "abxc", 1},
+		{"abxc", "abc", 1},
+		{"abc", "axc", 2},
+		{"abcabba", "cbabac", 5},
+		{"xaxbxc", "abc", 3},
+		{"abc", "xyz", 6},
+		{"abcdefgh", "aXcdeYgh", 4},
+		{strings.Repeat("abcd", 1000), strings.Repeat("abd", 1000), 1000},
+	}
+	for _, test := range tests {
+		a, b := difftestlines(test.a), difftestlines(test.b)
+		edits := myers(a, b)
+		// the edit script has to turn a into b
+		changes, i, j := 0, 0, 0
+		result := ""
+		for _, e := range edits {
+			switch e.op {
+			case editSame:
+				if e.old != i || e.new != j || a[i].normalized != b[j].normalized {
+					t.Errorf("%q -> %q: bad edit %v", test.a, test.b, e)
+				}
+				result += a[i].text
+				i++
+				j++
+			case editRemove:
+				if e.old != i {
+					t.Errorf("%q -> %q: bad edit %v", test.a, test.b, e)
+				}
+				changes++
+				i++
+			case editAdd:
+				if e.new != j {
+					t.Errorf("%q -> %q: bad edit %v", test.a, test.b, e)
+				}
+				result += b[j].text
+				changes++
+				j++
+			}
+		}
+		if result != test.b || i != len(a) || changes != test.changes {
+			t.Errorf("%q -> %q gives %q with %d changes, want %d", test.a, test.b, result, changes, test.changes)
+		}
+	}
+}
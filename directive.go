@@ -0,0 +1,585 @@
+package main
+
+/*
+	compiler directives in the source
+
+	NEC directives (!NEC$ in Fortran, #pragma _NEC in C/C++), OpenMP
+	(!$omp, #pragma omp) and the loop pragmas of gcc, clang and
+	icc are recognized in source lines, explained, and checked
+	against the assembly of the loop following them:
+	a vectorization directive is reflected if the loop contains
+	vector (VE) or packed SIMD (x86) instructions, a parallel
+	directive if the code is outlined or calls an OpenMP runtime,
+	for unrolling the most repeated arithmetic instruction is shown.
+*/
+
+import (
+	"fmt"
+	"strings"
+)
+
+// what a directive should be visible as in the assembly
+const (
+	expectNone = iota
+	expectVector
+	expectNoVector
+	expectParallel
+	expectUnroll
+)
+
+// Directive is a compiler directive found in a source line
+type Directive struct {
+	family string   // NEC, OpenMP, GCC, clang or Intel
+	words  []string // lower case words after the sentinel, clauses with their arguments
+}
+
+// sentinels of Fortran directives, in lower case
+var fortransentinels = []struct{ sentinel, family string }{
+	{"!nec$", "NEC"}, {"cnec$", "NEC"}, {"*nec$", "NEC"},
+	{"!$omp", "OpenMP"}, {"c$omp", "OpenMP"}, {"*$omp", "OpenMP"},
+	{"!gcc$", "GCC"},
+	{"!dir$", "Intel"}, {"cdir$", "Intel"}, {"!dec$", "Intel"}, {"cdec$", "Intel"},
+}
+
+// explanations of NEC directives
+var necdirectives = map[string]string{
+	"ivdep":               "ignore assumed vector dependencies in the following loop",
+	"vector":              "vectorize the following loop",
+	"novector":            "do not vectorize the following loop",
+	"shortloop":           "loop has at most the maximum vector length iterations, no strip mining loop",
+	"select_vector":       "prefer vectorization over parallelization",
+	"select_concurrent":   "prefer parallelization over vectorization",
+	"outerloop_unroll":    "unroll the outer loop, keeping the inner loop vectorized (default 4 times)",
+	"noouterloop_unroll":  "do not unroll the outer loop",
+	"unroll":              "unroll the following loop",
+	"nounroll":            "do not unroll the following loop",
+	"unroll_completely":   "unroll the following loop completely",
+	"loop_count":          "assumed iteration count of the following loop",
+	"list_vector":         "vectorize with list vector (indirect accesses with repeated indices)",
+	"nolist_vector":       "do not use list vector",
+	"gather_reorder":      "reorder indirect loads and stores to allow vectorization",
+	"nodep":               "assume no dependencies of indirect accesses",
+	"vreg":                "keep the array in vector registers",
+	"vwork":               "work vector for the array",
+	"retain":              "keep the array in the LLC (assigned to retain ADB)",
+	"on_adb":              "use the ADB for the array",
+	"vob":                 "vector overtaking of stores",
+	"vovertake":           "allow vector loads to overtake vector stores",
+	"novovertake":         "do not allow vector loads to overtake vector stores",
+	"packed_vector":       "use packed vector instructions (two 32 bit elements per element)",
+	"nopacked_vector":     "do not use packed vector instructions",
+	"assume":              "assume the loop length is at most the maximum vector length",
+	"noassume":            "do not assume the loop length",
+	"sparse":              "array is sparse, prefer list vector",
+	"nosync":              "no synchronization needed in the parallel loop",
+	"concurrent":          "parallelize the following loop automatically",
+	"noconcurrent":        "do not parallelize the following loop",
+	"parallel":            "parallelize the following loop",
+	"collapse":            "collapse the following nested loops into one",
+	"nofuse":              "do not fuse the following loop with others",
+	"fuse":                "fuse the following loop with the next one",
+	"interchange":         "interchange the following loop nest",
+	"nointerchange":       "do not interchange the following loop nest",
+	"inline":              "inline the following call",
+	"noinline":            "do not inline the following call",
+	"always_inline":       "always inline the function",
+	"move":                "move invariant code out of the loop",
+	"nomove":              "do not move invariant code out of the loop",
+	"atomic":              "the following update is atomic",
+	"arraycomb":           "combine arrays",
+	"alloc_on_vreg":       "allocate the array on vector registers",
+	"nofma":               "do not use fused multiply add",
+	"fma":                 "use fused multiply add",
+	"iterative":           "recurrence is vectorized with iterative instructions",
+	"select_vector_slice": "slice the vector loop",
+}
+
+// explanations of OpenMP constructs, compound constructs are explained word by word
+var ompconstructs = map[string]string{
+	"parallel":       "start a team of threads",
+	"for":            "distribute the loop iterations over the threads",
+	"do":             "distribute the loop iterations over the threads",
+	"simd":           "vectorize the loop",
+	"declare":        "declaration",
+	"target":         "offload to a device",
+	"teams":          "start a league of teams",
+	"distribute":     "distribute iterations over teams",
+	"loop":           "loop may be executed concurrently",
+	"taskloop":       "execute the loop iterations as tasks",
+	"task":           "create a task",
+	"taskwait":       "wait for child tasks",
+	"sections":       "distribute the sections over the threads",
+	"section":        "one section",
+	"single":         "executed by one thread only",
+	"master":         "executed by the master thread only",
+	"masked":         "executed by the primary thread only",
+	"critical":       "executed by one thread at a time",
+	"atomic":         "the following update is atomic",
+	"barrier":        "wait for all threads of the team",
+	"flush":          "make memory consistent",
+	"ordered":        "executed in loop order",
+	"workshare":      "divide the work of the block among the threads",
+	"threadprivate":  "variables private to each thread",
+	"end":            "end of the construct",
+	"data":           "map data to the device",
+	"update":         "update data on the device",
+	"enter":          "map data to the device",
+	"exit":           "unmap data from the device",
+	"reduction":      "reduction over the threads",
+	"requires":       "requirements of the code",
+	"scan":           "scan over the loop",
+	"tile":           "tile the loop nest",
+	"unroll":         "unroll the loop",
+	"metadirective":  "select a directive by context",
+	"cancel":         "cancel the construct",
+	"taskgroup":      "wait for the tasks of the group",
+	"taskyield":      "task may be suspended",
+	"cancellation":   "cancellation point",
+	"point":          "",
+	"variant":        "function variant",
+	"mapper":         "declare a mapper",
+	"allocate":       "allocate memory",
+	"nowait":         "no barrier at the end",
+	"nogroup":        "no implicit taskgroup",
+	"untied":         "task may change threads",
+	"mergeable":      "task may be merged",
+	"inbranch":       "called in a branch",
+	"notinbranch":    "never called in a branch",
+	"seq_cst":        "sequentially consistent",
+	"read":           "atomic read",
+	"write":          "atomic write",
+	"capture":        "atomic capture",
+	"compare":        "atomic compare",
+	"relaxed":        "relaxed memory order",
+	"acquire":        "acquire memory order",
+	"release":        "release memory order",
+	"acq_rel":        "acquire release memory order",
+	"default":        "default data sharing",
+	"private":        "variables private to each thread",
+	"firstprivate":   "private variables initialized with the value before",
+	"lastprivate":    "private variables, value of last iteration is kept",
+	"shared":         "variables shared by all threads",
+	"schedule":       "how iterations are distributed",
+	"collapse":       "number of loops collapsed into one",
+	"num_threads":    "number of threads",
+	"if":             "only parallel if condition holds",
+	"safelen":        "maximum distance of dependent iterations",
+	"simdlen":        "preferred vector length",
+	"aligned":        "pointers are aligned",
+	"linear":         "variables linear in the iteration",
+	"uniform":        "arguments equal for all lanes",
+	"map":            "data mapped to the device",
+	"device":         "device to use",
+	"depend":         "task dependencies",
+	"proc_bind":      "thread affinity",
+	"copyin":         "copy threadprivate variables into the team",
+	"copyprivate":    "broadcast of private variables",
+	"num_teams":      "number of teams",
+	"thread_limit":   "maximum threads per team",
+	"dist_schedule":  "how iterations are distributed over teams",
+	"grainsize":      "iterations per task",
+	"num_tasks":      "number of tasks",
+	"priority":       "task priority",
+	"final":          "task is final",
+	"order":          "iteration order",
+	"nontemporal":    "accesses are nontemporal",
+	"in_reduction":   "task participates in reduction",
+	"task_reduction": "reduction over tasks",
+	"is_device_ptr":  "pointers are device pointers",
+	"use_device_ptr": "use device pointers",
+	"partial":        "partial unroll",
+	"full":           "full unroll",
+	"sizes":          "tile sizes",
+	"bind":           "binding of the loop",
+}
+
+// explanations of gcc, clang and icc loop pragmas, clang ones as loop <word>
+var looppragmas = map[string]string{
+	"ivdep":                    "ignore assumed vector dependencies in the following loop",
+	"unroll":                   "unroll the following loop",
+	"nounroll":                 "do not unroll the following loop",
+	"novector":                 "do not vectorize the following loop",
+	"vector":                   "vectorize the following loop",
+	"simd":                     "vectorize the following loop",
+	"optimize":                 "optimization options for following functions",
+	"loop vectorize":           "enable or disable vectorization of the following loop",
+	"loop vectorize_width":     "vector width of the following loop",
+	"loop interleave":          "enable or disable interleaving of the following loop",
+	"loop interleave_count":    "interleave count of the following loop",
+	"loop unroll":              "enable, disable or fully unroll the following loop",
+	"loop unroll_count":        "unroll count of the following loop",
+	"loop distribute":          "enable or disable loop distribution",
+	"loop vectorize_predicate": "enable or disable predicated vectorization",
+	"loop pipeline":            "enable or disable software pipelining",
+	"loop_count":               "assumed iteration count of the following loop",
+	"distribute_point":         "split the loop here",
+	"forceinline":              "force inlining",
+	"noinline":                 "do not inline",
+	"inline":                   "inline the following call",
+}
+
+// parsedirective returns the directive of a source line, false if it is none
+func parsedirective(line string) (Directive, bool) {
+	text := strings.ToLower(strings.TrimSpace(line))
+	if strings.HasPrefix(text, "#") {
+		fields := strings.Fields(strings.TrimSpace(text[1:]))
+		if len(fields) < 2 || fields[0] != "pragma" {
+			return Directive{}, false
+		}
+		fields = fields[1:]
+		switch fields[0] {
+		case "_nec", "cdir":
+			return Directive{"NEC", splitwords(fields[1:])}, true
+		case "omp":
+			return Directive{"OpenMP", splitwords(fields[1:])}, true
+		case "gcc":
+			return Directive{"GCC", splitwords(fields[1:])}, true
+		case "clang":
+			return Directive{"clang", splitwords(fields[1:])}, true
+		case "ivdep", "unroll", "nounroll", "simd", "vector", "novector", "loop_count", "distribute_point",
+			"forceinline", "noinline", "inline":
+			return Directive{"Intel", splitwords(fields)}, true
+		}
+		return Directive{}, false
+	}
+	// fixed form directives start in column 1, so do not trim left for them
+	original := strings.ToLower(line)
+	for _, s := range fortransentinels {
+		if strings.HasPrefix(text, s.sentinel) && (s.sentinel[0] == '!' || strings.HasPrefix(original, s.sentinel)) {
+			words := strings.TrimSpace(text[len(s.sentinel):])
+			// comments behind the directive
+			if c := strings.Index(words, "!"); c >= 0 {
+				words = words[:c]
+			}
+			return Directive{s.family, splitwords(strings.Fields(words))}, true
+		}
+	}
+	return Directive{}, false
+}
+
+// splitwords splits fields into words, keeping clause arguments like (n) with their clause
+func splitwords(fields []string) []string {
+	text := strings.Join(fields, " ")
+	words := make([]string, 0, len(fields))
+	depth := 0
+	start := 0
+	for i := 0; i <= len(text); i++ {
+		if i == len(text) || depth == 0 && (text[i] == ' ' || text[i] == ',') {
+			if w := strings.TrimSpace(text[start:i]); w != "" {
+				// allow a space before the argument, like unroll (4)
+				if strings.HasPrefix(w, "(") && len(words) > 0 {
+					words[len(words)-1] += w
+				} else {
+					words = append(words, w)
+				}
+			}
+			start = i + 1
+			continue
+		}
+		switch text[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+	}
+	return words
+}
+
+// wordname returns a word without its argument
+func wordname(word string) string {
+	if i := strings.Index(word, "("); i >= 0 {
+		return strings.TrimSpace(word[:i])
+	}
+	return word
+}
+
+// wordargument returns the argument of a word, or ""
+func wordargument(word string) string {
+	i := strings.Index(word, "(")
+	j := strings.LastIndex(word, ")")
+	if i >= 0 && j > i {
+		return strings.TrimSpace(word[i+1 : j])
+	}
+	return ""
+}
+
+// explain returns the explanation lines of a directive
+func (d Directive) explain() []string {
+	lines := make([]string, 0, 4)
+	lines = append(lines, d.family+" directive: "+strings.Join(d.words, " "))
+	if len(d.words) == 0 {
+		return lines
+	}
+	var table map[string]string
+	switch d.family {
+	case "NEC":
+		table = necdirectives
+	case "OpenMP":
+		table = ompconstructs
+	default:
+		table = looppragmas
+	}
+	found := make([]string, 0, len(d.words))
+	for i, w := range d.words {
+		name := wordname(w)
+		if d.family == "clang" && i > 0 && d.words[0] == "loop" {
+			name = "loop " + name
+		}
+		if e, ok := table[name]; ok && e != "" {
+			if arg := wordargument(w); arg != "" {
+				e += " (" + arg + ")"
+			}
+			found = append(found, w+": "+e)
+		}
+	}
+	if len(found) > 0 {
+		lines = append(lines, strings.Join(found, ", "))
+	} else {
+		lines = append(lines, "unknown directive")
+	}
+	return lines
+}
+
+// expectations returns what the directive should show in the assembly
+func (d Directive) expectations() []int {
+	expect := make([]int, 0, 2)
+	add := func(e int) {
+		for _, x := range expect {
+			if x == e {
+				return
+			}
+		}
+		expect = append(expect, e)
+	}
+	for i, w := range d.words {
+		name := wordname(w)
+		arg := wordargument(w)
+		switch d.family {
+		case "NEC":
+			switch name {
+			case "ivdep", "vector", "shortloop", "list_vector", "gather_reorder", "nodep", "assume":
+				add(expectVector)
+			case "novector":
+				add(expectNoVector)
+			case "concurrent", "parallel":
+				add(expectParallel)
+			case "unroll", "outerloop_unroll", "unroll_completely":
+				add(expectUnroll)
+			}
+		case "OpenMP":
+			if i > 0 && d.words[0] == "declare" || name == "end" {
+				return nil
+			}
+			switch name {
+			case "simd":
+				add(expectVector)
+			case "parallel", "teams", "taskloop":
+				add(expectParallel)
+			case "unroll":
+				add(expectUnroll)
+			}
+		case "clang":
+			switch {
+			case name == "vectorize" && arg == "disable", name == "vectorize_width" && arg == "1":
+				add(expectNoVector)
+			case name == "vectorize" || name == "vectorize_width" || name == "interleave" && arg != "disable":
+				add(expectVector)
+			case name == "unroll" && arg != "disable", name == "unroll_count":
+				add(expectUnroll)
+			}
+		default:
+			switch name {
+			case "ivdep", "simd", "vector":
+				add(expectVector)
+			case "novector":
+				add(expectNoVector)
+			case "unroll":
+				add(expectUnroll)
+			}
+		}
+	}
+	return expect
+}
+
+// isdirectiveline tells if a source line holds a recognized directive
+func isdirectiveline(line string) bool {
+	_, ok := parsedirective(line)
+	return ok
+}
+
+// iscodeline tells if a source line contains code, not only white space, comments and directives
+func iscodeline(lang int, line string) bool {
+	if isdirectiveline(line) {
+		return false
+	}
+	colors, _ := highlightline(lang, line, hlStateNormal)
+	for i, c := range colors {
+		if line[i] != ' ' && line[i] != '\t' && c != hlComment && c != hlPreprocessor {
+			return true
+		}
+	}
+	return false
+}
+
+// ompruntime are parts of names of OpenMP runtime calls and outlined functions
+var ompruntime = []string{"GOMP_", "__kmpc_", "_omp_fn", "omp_outlined", "__vthr", "__ompc_", "$par"}
+
+// symbolbefore returns the last label in front of line which is not a local label, or ""
+func (a *AssemblerFile) symbolbefore(line int) string {
+	for l := line; l > 0; l-- {
+		if name := labelof(a.filebuffer.GetLine(l)); name != "" && name[0] != '.' {
+			return name
+		}
+	}
+	return ""
+}
+
+// isparallel tells if a line is in an outlined parallel region or its function calls the OpenMP runtime
+func (a *AssemblerFile) isparallel(line int) (bool, string) {
+	// outlined functions are often local symbols, so they are not in functions
+	name := a.symbolbefore(line)
+	for _, r := range ompruntime {
+		if strings.Contains(name, r) {
+			return true, "code is in outlined function " + symbolname(name)
+		}
+	}
+	fi := a.functionof(line)
+	if fi < 0 {
+		return false, "code is not in an outlined function"
+	}
+	f := a.functions[fi]
+	for l := f.start; l <= f.end; l++ {
+		ins, ok := parseInstruction(a.filebuffer.GetLine(l))
+		if !ok || a.classify(ins) != classCall {
+			continue
+		}
+		for _, r := range ompruntime {
+			if strings.Contains(strings.Join(ins.operands, ","), r) {
+				return true, "function calls the OpenMP runtime: " + ins.mnemonic + " " + strings.Join(ins.operands, ",")
+			}
+		}
+	}
+	return false, "no outlined function and no call of the OpenMP runtime found"
+}
+
+// isvectorloop tells if a loop contains vector instructions, with a description
+func (a *AssemblerFile) isvectorloop(li int) (bool, string) {
+	loop := a.loops[li]
+	if a.ve {
+		blocks := a.buildblocks(loop.function)
+		mix := a.loopmix(li, blocks)
+		vector := mix.classes[classVector] + mix.classes[classVectorLoad] + mix.classes[classVectorStore] + mix.gathers()
+		if vector == 0 {
+			return false, fmt.Sprintf("no vector instructions, %d scalar", mix.scalars())
+		}
+		return true, fmt.Sprintf("%d vector instructions, %d scalar, score %.0f%%", vector, mix.scalars(), mix.score())
+	}
+	mix := a.widthmix(loop.head, loop.latch, -1)
+	packed := mix.widths[width128] + mix.widths[width256] + mix.widths[width512]
+	if packed == 0 {
+		return false, fmt.Sprintf("no packed SIMD instructions, %d scalar", mix.widths[widthScalar])
+	}
+	return true, fmt.Sprintf("%d packed SIMD instructions (mostly %s bit), %d scalar", packed,
+		widthnames[mix.dominant()], mix.widths[widthScalar])
+}
+
+// unrollhint returns the most repeated arithmetic instruction of a loop, a hint for the unroll factor
+func (a *AssemblerFile) unrollhint(li int) string {
+	loop := a.loops[li]
+	counts := make(map[string]int)
+	best := ""
+	for l := loop.head; l <= loop.latch; l++ {
+		if a.loopof(l) != li {
+			continue // only the loop itself, not nested loops
+		}
+		ins, ok := parseInstruction(a.filebuffer.GetLine(l))
+		if !ok {
+			continue
+		}
+		switch a.classify(ins) {
+		case classScalarFP, classVector:
+			counts[ins.mnemonic]++
+			if best == "" || counts[ins.mnemonic] > counts[best] {
+				best = ins.mnemonic
+			}
+		}
+	}
+	if best == "" {
+		return "no floating point instructions in the loop body"
+	}
+	return fmt.Sprintf("%d times %s in the loop body", counts[best], best)
+}
+
+// loopsof returns the loops containing any of the lines, in order of the lines
+func (a *AssemblerFile) loopsof(lines []int) []int {
+	loops := make([]int, 0, 4)
+	seen := make(map[int]bool)
+	for _, l := range lines {
+		if li := a.loopof(l); li >= 0 && !seen[li] {
+			seen[li] = true
+			loops = append(loops, li)
+		}
+	}
+	return loops
+}
+
+// directivecheck returns lines telling if the assembly of the loop following a directive reflects it,
+// statement are the assembler lines of the loop statement, body those of the statement after it
+func (a *AssemblerFile) directivecheck(d Directive, statement, body []int) []string {
+	expect := d.expectations()
+	if len(expect) == 0 {
+		return nil
+	}
+	if len(statement)+len(body) == 0 {
+		return []string{"no assembly found for the following statement"}
+	}
+	// loops containing the code of the loop statement, or of its body if the statement has no code inside a loop
+	loops := a.loopsof(statement)
+	if len(loops) == 0 {
+		loops = a.loopsof(body)
+	}
+	lines := make([]string, 0, len(loops)+2)
+	for _, e := range expect {
+		if e == expectParallel {
+			ok, why := a.isparallel(append(statement, body...)[0])
+			lines = append(lines, verdict(ok, "parallel")+why)
+			continue
+		}
+		if len(loops) == 0 {
+			lines = append(lines, "no loop found in the assembly of the following statement")
+			continue
+		}
+		details := make([]string, 0, len(loops))
+		vectorized := false
+		for _, li := range loops {
+			name := a.loopname(li) + ": "
+			switch e {
+			case expectVector, expectNoVector:
+				ok, why := a.isvectorloop(li)
+				vectorized = vectorized || ok
+				details = append(details, name+why)
+			case expectUnroll:
+				details = append(details, name+a.unrollhint(li))
+			}
+		}
+		// remainder loops stay scalar, so one vectorized loop is enough
+		switch e {
+		case expectVector:
+			lines = append(lines, verdict(vectorized, "vectorized")+"loops "+strings.Join(details, "; "))
+		case expectNoVector:
+			lines = append(lines, verdict(!vectorized, "kept scalar")+"loops "+strings.Join(details, "; "))
+		case expectUnroll:
+			lines = append(lines, "unrolling, loops "+strings.Join(details, "; "))
+		}
+	}
+	return lines
+}
+
+// verdict formats if an expectation holds
+func verdict(ok bool, what string) string {
+	if ok {
+		return what + " as requested, "
+	}
+	return "NOT " + what + ", "
+}
@@ -0,0 +1,710 @@
+package main
+
+/*
+	variables in registers from debug information
+
+	gcc -g -S writes the DWARF sections as assembler data, one
+	directive per value, with labels in the code as addresses.
+	we decode .debug_abbrev, .debug_info and the location lists of
+	.debug_loclists (.debug_loc for DWARF 4) from these directives
+	without assembling them: every value of a directive is an item,
+	an attribute consumes items according to its form, only blocks
+	need the byte sizes of items. addresses are label expressions
+	like .LVL30-.Ltext0, where the first label gives the line.
+	for variables and parameters living in a register we keep the
+	lines on which the location is valid, so register operands can
+	be annotated with variable names like %xmm0 = sum.
+*/
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// showvariables enables the annotation of register operands with variable names
+var showvariables = true
+
+// DWARF tags, attributes, forms and operations we need
+const (
+	dwTagFormalParameter = 0x05
+	dwTagVariable        = 0x34
+
+	dwAtLocation       = 0x02
+	dwAtName           = 0x03
+	dwAtLowPc          = 0x11
+	dwAtHighPc         = 0x12
+	dwAtAbstractOrigin = 0x31
+	dwAtSpecification  = 0x47
+	dwAtType           = 0x49
+	dwAtRanges         = 0x55
+
+	dwFormAddr          = 0x01
+	dwFormBlock2        = 0x03
+	dwFormBlock4        = 0x04
+	dwFormString        = 0x08
+	dwFormBlock         = 0x09
+	dwFormBlock1        = 0x0a
+	dwFormStrp          = 0x0e
+	dwFormRefAddr       = 0x10
+	dwFormIndirect      = 0x16
+	dwFormExprloc       = 0x18
+	dwFormFlagPresent   = 0x19
+	dwFormData16        = 0x1e
+	dwFormLineStrp      = 0x1f
+	dwFormImplicitConst = 0x21
+
+	dwOpReg0     = 0x50
+	dwOpReg31    = 0x6f
+	dwOpRegx     = 0x90
+	dwOpPiece    = 0x93
+	dwOpBitPiece = 0x9d
+)
+
+// sizes of data directives
+var datasizes = map[string]int{
+	".byte": 1, ".value": 2, ".2byte": 2, ".short": 2, ".hword": 2,
+	".long": 4, ".4byte": 4, ".int": 4, ".quad": 8, ".8byte": 8,
+}
+
+// x86-64 general purpose registers in DWARF order
+var x86dwarfregisters = []string{"%rax", "%rdx", "%rcx", "%rbx", "%rsi", "%rdi", "%rbp", "%rsp"}
+
+var relabelexpr = regexp.MustCompile(`^[A-Za-z_.$][A-Za-z0-9_.$@]*`)
+
+// RegisterVariable is a variable or parameter living in a register on a range of lines
+type RegisterVariable struct {
+	name        string
+	typename    string
+	register    string // register as numbered by DWARF, like %xmm0 for 17
+	first, last int    // lines in assembler file
+}
+
+// dataitem is one value of a data directive in a debug section
+type dataitem struct {
+	size  int    // bytes, 0 if not known (leb128 of label expressions)
+	value int64  // value of numbers
+	expr  string // value as written if it is not a number
+	text  string // value of strings
+}
+
+// debuglabel is the position of a label in the items of a debug section
+type debuglabel struct {
+	section string
+	item    int
+}
+
+// attrvalue is the value of an attribute, blocks have several items
+type attrvalue struct {
+	form  int
+	items []dataitem
+}
+
+// abbrev is an entry of the abbreviation table, describing the layout of a DIE
+type abbrev struct {
+	tag      int
+	children bool
+	attrs    [][3]int64 // attribute, form, implicit constant
+}
+
+// die is a debugging information entry
+type die struct {
+	tag    int
+	attrs  map[int]attrvalue
+	parent *die
+	unit   int // offset of its unit, references are relative to it
+}
+
+// debuginfo is the decoded debug information of an assembler file
+type debuginfo struct {
+	sections map[string][]dataitem
+	labels   map[string]debuglabel
+	dies     map[int]*die // by offset in .debug_info
+	version  int
+}
+
+// itemreader reads the items of a section in order and counts their bytes
+type itemreader struct {
+	items  []dataitem
+	pos    int
+	offset int
+}
+
+func (r *itemreader) done() bool {
+	return r.pos >= len(r.items)
+}
+
+func (r *itemreader) next() dataitem {
+	if r.done() {
+		return dataitem{}
+	}
+	item := r.items[r.pos]
+	r.pos++
+	r.offset += item.size
+	return item
+}
+
+// block reads items until size bytes are read, items of unknown size count as one byte
+func (r *itemreader) block(size int) []dataitem {
+	block := make([]dataitem, 0, 4)
+	for read := 0; read < size && !r.done(); {
+		item := r.next()
+		read += maxi(item.size, 1)
+		block = append(block, item)
+	}
+	return block
+}
+
+// form reads the value of an attribute with the given form
+func (r *itemreader) form(form int, implicit int64) attrvalue {
+	switch form {
+	case dwFormFlagPresent:
+		return attrvalue{form, []dataitem{{value: 1}}}
+	case dwFormImplicitConst:
+		return attrvalue{form, []dataitem{{value: implicit}}}
+	case dwFormIndirect:
+		return r.form(int(r.next().value), implicit)
+	case dwFormBlock1, dwFormBlock2, dwFormBlock4, dwFormBlock, dwFormExprloc:
+		return attrvalue{form, r.block(int(r.next().value))}
+	case dwFormData16:
+		return attrvalue{form, r.block(16)}
+	}
+	return attrvalue{form, []dataitem{r.next()}}
+}
+
+// lebsize returns the number of bytes of a leb128 encoded number
+func lebsize(v int64, signed bool) int {
+	size := 1
+	for {
+		if signed && v >= -64 && v < 64 || !signed && uint64(v) < 128 {
+			return size
+		}
+		if signed {
+			v >>= 7
+		} else {
+			v = int64(uint64(v) >> 7)
+		}
+		size++
+	}
+}
+
+// parsedataitem parses one value of a data directive
+func parsedataitem(value string, size int, leb, signed bool) dataitem {
+	value = strings.TrimSpace(value)
+	v, err := strconv.ParseInt(value, 0, 64)
+	if err != nil {
+		u, uerr := strconv.ParseUint(value, 0, 64)
+		if uerr != nil {
+			return dataitem{size: size, expr: value}
+		}
+		v = int64(u)
+	}
+	if leb {
+		size = lebsize(v, signed)
+	}
+	return dataitem{size: size, value: v}
+}
+
+// unquote returns the contents of an assembler string and the rest of the line
+func unquote(s string) (string, string) {
+	if len(s) == 0 || s[0] != '"' {
+		return "", s
+	}
+	for i := 1; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+		} else if s[i] == '"' {
+			if text, err := strconv.Unquote(s[:i+1]); err == nil {
+				return text, s[i+1:]
+			}
+			return s[1:i], s[i+1:]
+		}
+	}
+	return s[1:], ""
+}
+
+// readdebugdata collects the items and labels of all debug sections
+func (a *AssemblerFile) readdebugdata() *debuginfo {
+	d := &debuginfo{sections: make(map[string][]dataitem), labels: make(map[string]debuglabel), dies: make(map[int]*die)}
+	for l, section := range a.sectionlines() {
+		if !strings.HasPrefix(section, ".debug") {
+			continue
+		}
+		line := a.filebuffer.GetLine(l)
+		if label := labelof(line); label != "" {
+			d.labels[label] = debuglabel{section, len(d.sections[section])}
+			continue
+		}
+		line = strings.TrimSpace(line)
+		pos := strings.IndexAny(line, " \t")
+		if pos == -1 {
+			continue
+		}
+		directive, rest := line[:pos], strings.TrimSpace(line[pos:])
+		switch directive {
+		case ".string", ".asciz", ".ascii":
+			text, _ := unquote(rest)
+			size := len(text) + 1
+			if directive == ".ascii" {
+				size--
+			}
+			d.sections[section] = append(d.sections[section], dataitem{size: size, text: text})
+			continue
+		}
+		if c := strings.IndexByte(rest, '#'); c >= 0 {
+			rest = rest[:c]
+		}
+		size, data := datasizes[directive]
+		leb := directive == ".uleb128" || directive == ".sleb128"
+		if !data && !leb {
+			continue
+		}
+		for _, value := range strings.Split(rest, ",") {
+			d.sections[section] = append(d.sections[section], parsedataitem(value, size, leb, directive == ".sleb128"))
+		}
+	}
+	return d
+}
+
+// firstlabel returns the first label of an expression like .LVL34-1-.Ltext0
+func firstlabel(expr string) string {
+	return relabelexpr.FindString(strings.TrimLeft(expr, "( "))
+}
+
+// reader returns a reader of a section starting at a label
+func (d *debuginfo) reader(label, section string) (*itemreader, bool) {
+	pos, ok := d.labels[label]
+	if !ok || pos.section != section {
+		return nil, false
+	}
+	return &itemreader{items: d.sections[section], pos: pos.item}, true
+}
+
+// abbrevs reads the abbreviation table starting at label
+func (d *debuginfo) abbrevs(label string) map[int]abbrev {
+	r, ok := d.reader(label, ".debug_abbrev")
+	if !ok {
+		return nil
+	}
+	table := make(map[int]abbrev)
+	for !r.done() {
+		code := int(r.next().value)
+		if code == 0 {
+			break
+		}
+		ab := abbrev{tag: int(r.next().value), children: r.next().value != 0}
+		for !r.done() {
+			attr, form := r.next().value, r.next().value
+			if attr == 0 && form == 0 {
+				break
+			}
+			implicit := int64(0)
+			if form == dwFormImplicitConst {
+				implicit = r.next().value
+			}
+			ab.attrs = append(ab.attrs, [3]int64{attr, form, implicit})
+		}
+		table[code] = ab
+	}
+	return table
+}
+
+// readinfo reads the DIEs of all units in .debug_info
+func (d *debuginfo) readinfo() {
+	r := &itemreader{items: d.sections[".debug_info"]}
+	for !r.done() {
+		unit := r.offset
+		if r.next().value == 0xffffffff {
+			return // 64 bit DWARF
+		}
+		d.version = int(r.next().value)
+		var abbrevlabel string
+		if d.version >= 5 {
+			if unittype := r.next().value; unittype != 1 {
+				return // only compile units
+			}
+			r.next() // address size
+			abbrevlabel = r.next().expr
+		} else {
+			abbrevlabel = r.next().expr
+			r.next() // address size
+		}
+		abbrevs := d.abbrevs(firstlabel(abbrevlabel))
+		if abbrevs == nil {
+			return
+		}
+		var parent *die
+		for !r.done() {
+			offset := r.offset
+			code := int(r.next().value)
+			if code == 0 {
+				if parent != nil {
+					parent = parent.parent
+				}
+			} else {
+				ab, ok := abbrevs[code]
+				if !ok {
+					return // lost track of the items
+				}
+				e := &die{tag: ab.tag, attrs: make(map[int]attrvalue, len(ab.attrs)), parent: parent, unit: unit}
+				for _, attr := range ab.attrs {
+					e.attrs[int(attr[0])] = r.form(int(attr[1]), attr[2])
+				}
+				d.dies[offset] = e
+				if ab.children {
+					parent = e
+				}
+			}
+			if parent == nil {
+				break // end of unit
+			}
+		}
+	}
+}
+
+// ref returns the DIE a reference attribute refers to
+func (d *debuginfo) ref(e *die, v attrvalue) *die {
+	offset := int(v.items[0].value)
+	if v.form != dwFormRefAddr {
+		offset += e.unit
+	}
+	return d.dies[offset]
+}
+
+// attr returns an attribute of a DIE, following abstract origins and specifications
+func (d *debuginfo) attr(e *die, attr int) (attrvalue, bool) {
+	for depth := 0; e != nil && depth < 8; depth++ {
+		if v, ok := e.attrs[attr]; ok {
+			return v, true
+		}
+		origin, ok := e.attrs[dwAtAbstractOrigin]
+		if !ok {
+			origin, ok = e.attrs[dwAtSpecification]
+		}
+		if !ok {
+			break
+		}
+		e = d.ref(e, origin)
+	}
+	return attrvalue{}, false
+}
+
+// stringof returns the string value of an attribute
+func (d *debuginfo) stringof(v attrvalue) string {
+	switch v.form {
+	case dwFormString:
+		return v.items[0].text
+	case dwFormStrp, dwFormLineStrp:
+		if pos, ok := d.labels[firstlabel(v.items[0].expr)]; ok && pos.item < len(d.sections[pos.section]) {
+			return d.sections[pos.section][pos.item].text
+		}
+	}
+	return ""
+}
+
+// name returns the name of a DIE, "" if it has none
+func (d *debuginfo) name(e *die) string {
+	if v, ok := d.attr(e, dwAtName); ok {
+		return d.stringof(v)
+	}
+	return ""
+}
+
+// typename returns the C notation of the type of a DIE
+func (d *debuginfo) typename(e *die) string {
+	prefix, suffix := "", ""
+	for depth := 0; depth < 16; depth++ {
+		v, ok := d.attr(e, dwAtType)
+		if !ok {
+			return prefix + "void" + suffix
+		}
+		if e = d.ref(e, v); e == nil {
+			return prefix + "?" + suffix
+		}
+		switch e.tag {
+		case 0x0f, 0x10, 0x42: // pointer, reference, rvalue reference
+			suffix = "*" + suffix
+		case 0x26:
+			prefix += "const "
+		case 0x35:
+			prefix += "volatile "
+		case 0x01:
+			suffix += "[]"
+		case 0x15:
+			return prefix + "function" + suffix
+		case 0x13, 0x17, 0x04, 0x02: // struct, union, enum, class
+			return prefix + map[int]string{0x13: "struct ", 0x17: "union ", 0x04: "enum ", 0x02: "class "}[e.tag] + d.name(e) + suffix
+		case 0x37: // restrict
+		default:
+			if name := d.name(e); name != "" {
+				return prefix + name + suffix
+			}
+		}
+	}
+	return prefix + "?" + suffix
+}
+
+// lineof returns the line of the first label of an address expression, 0 if there is none
+func (a *AssemblerFile) lineof(item dataitem) int {
+	return a.labels[firstlabel(item.expr)]
+}
+
+// linerange is a range of lines with the location expression valid on it
+type linerange struct {
+	first, last int
+	expr        []dataitem
+}
+
+// scope returns the lines of the innermost DIE with an address range containing e
+func (a *AssemblerFile) scope(d *debuginfo, e *die) []linerange {
+	for p := e.parent; p != nil; p = p.parent {
+		if low, ok := p.attrs[dwAtLowPc]; ok {
+			if high, ok := p.attrs[dwAtHighPc]; ok {
+				first, end := a.lineof(low.items[0]), a.lineof(high.items[0])
+				if first > 0 && end > first {
+					return []linerange{{first, end - 1, nil}}
+				}
+			}
+			return nil
+		}
+		if ranges, ok := p.attrs[dwAtRanges]; ok {
+			if d.version >= 5 {
+				return a.listranges(d, ranges.items[0].expr, ".debug_rnglists", false)
+			}
+			return a.listranges(d, ranges.items[0].expr, ".debug_ranges", false)
+		}
+	}
+	return nil
+}
+
+// listranges reads a range list or a location list starting at label
+func (a *AssemblerFile) listranges(d *debuginfo, label, section string, location bool) []linerange {
+	r, ok := d.reader(firstlabel(label), section)
+	if !ok {
+		return nil
+	}
+	ranges := make([]linerange, 0, 8)
+	add := func(from, to dataitem, expr []dataitem) {
+		if first, end := a.lineof(from), a.lineof(to); first > 0 && end > first {
+			ranges = append(ranges, linerange{first, end - 1, expr})
+		}
+	}
+	expr := func() []dataitem {
+		if !location {
+			return nil
+		}
+		return r.block(int(r.next().value))
+	}
+	for !r.done() {
+		if d.version < 5 {
+			// DWARF 4: pairs of addresses, followed by location expressions
+			start, end := r.next(), r.next()
+			if start.expr == "" && end.expr == "" && start.value == 0 && end.value == 0 {
+				break
+			}
+			if start.expr == "" && start.value == -1 {
+				continue // base address selection
+			}
+			add(start, end, expr())
+			continue
+		}
+		kind := r.next().value
+		if !location && kind >= 5 {
+			kind++ // range lists have no default location entry
+		}
+		switch kind {
+		case 0: // end of list
+			return ranges
+		case 1: // base addressx
+			r.next()
+		case 2, 3: // startx endx, startx length, indices into .debug_addr
+			r.next()
+			r.next()
+			expr()
+		case 4, 7: // offset pair, start end
+			start, end := r.next(), r.next()
+			add(start, end, expr())
+		case 5: // default location
+			expr()
+		case 6: // base address
+			r.next()
+		case 8: // start length, the length is written as end-start
+			start, length := r.next(), r.next()
+			add(start, length, expr())
+		case 9: // GNU view pair of the next entry, written by -gvariable-location-views=incompat5
+			r.next()
+			r.next()
+		default:
+			return ranges
+		}
+	}
+	return ranges
+}
+
+// dwarfregister returns the name of a DWARF register number, "" if it is not known
+func dwarfregister(n int64, ve bool) string {
+	if ve {
+		switch {
+		case n < 64:
+			return fmt.Sprintf("%%s%d", n)
+		case n < 128:
+			return fmt.Sprintf("%%v%d", n-64)
+		case n < 144:
+			return fmt.Sprintf("%%vm%d", n-128)
+		}
+		return ""
+	}
+	switch {
+	case n < 8:
+		return x86dwarfregisters[n]
+	case n < 16:
+		return fmt.Sprintf("%%r%d", n)
+	case n >= 17 && n <= 32:
+		return fmt.Sprintf("%%xmm%d", n-17)
+	case n >= 67 && n <= 82:
+		return fmt.Sprintf("%%xmm%d", n-67+16)
+	case n >= 118 && n <= 125:
+		return fmt.Sprintf("%%k%d", n-118)
+	}
+	return ""
+}
+
+// exprregisters returns the registers of a location expression which only names registers, nil for other expressions
+func exprregisters(expr []dataitem, ve bool) []string {
+	var registers []string
+	for i := 0; i < len(expr); i++ {
+		if expr[i].expr != "" {
+			return nil
+		}
+		op := expr[i].value
+		switch {
+		case op >= dwOpReg0 && op <= dwOpReg31:
+			registers = append(registers, dwarfregister(op-dwOpReg0, ve))
+		case op == dwOpRegx && i+1 < len(expr):
+			i++
+			registers = append(registers, dwarfregister(expr[i].value, ve))
+		case op == dwOpPiece:
+			i++
+		case op == dwOpBitPiece:
+			i += 2
+		default:
+			return nil // computed values, memory locations
+		}
+	}
+	return registers
+}
+
+// findvariables decodes the debug information and collects the variables living in registers
+func (a *AssemblerFile) findvariables() {
+	a.regvars = nil
+	a.regvarsat = make(map[int][]int)
+	d := a.readdebugdata()
+	if len(d.sections[".debug_info"]) == 0 {
+		return
+	}
+	d.readinfo()
+	loclists := ".debug_loclists"
+	if d.version < 5 {
+		loclists = ".debug_loc"
+	}
+	for _, e := range d.dies {
+		if e.tag != dwTagVariable && e.tag != dwTagFormalParameter {
+			continue
+		}
+		location, ok := e.attrs[dwAtLocation]
+		if !ok || len(location.items) == 0 {
+			continue
+		}
+		var ranges []linerange
+		if location.form == dwFormExprloc || location.form == dwFormBlock1 {
+			if exprregisters(location.items, a.ve) == nil {
+				continue
+			}
+			for _, r := range a.scope(d, e) {
+				ranges = append(ranges, linerange{r.first, r.last, location.items})
+			}
+		} else if location.items[0].expr != "" {
+			ranges = a.listranges(d, location.items[0].expr, loclists, true)
+		}
+		name := d.name(e)
+		if name == "" {
+			continue
+		}
+		typename := d.typename(e)
+		for _, r := range ranges {
+			for _, register := range exprregisters(r.expr, a.ve) {
+				if register != "" {
+					a.regvars = append(a.regvars, RegisterVariable{name, typename, register, r.first, r.last})
+				}
+			}
+		}
+	}
+	sort.Slice(a.regvars, func(i, j int) bool {
+		vi, vj := a.regvars[i], a.regvars[j]
+		if vi.first != vj.first {
+			return vi.first < vj.first
+		}
+		return vi.name < vj.name
+	})
+	for i, v := range a.regvars {
+		for l := v.first; l <= v.last; l++ {
+			a.regvarsat[l] = append(a.regvarsat[l], i)
+		}
+	}
+}
+
+// variablesin returns the names of the variables in a register at a line, separated by /
+func (a *AssemblerFile) variablesin(line int, register string) string {
+	names := make([]string, 0, 2)
+	seen := make(map[string]bool)
+	for _, i := range a.regvarsat[line] {
+		v := a.regvars[i]
+		if canonicalregister(v.register, a.ve) == register && !seen[v.name] {
+			seen[v.name] = true
+			names = append(names, v.name)
+		}
+	}
+	return strings.Join(names, "/")
+}
+
+// variablesof annotates the register operands of the instruction in line with variable names, like %xmm0 = sum
+func (a *AssemblerFile) variablesof(line int) string {
+	if len(a.regvarsat[line]) == 0 {
+		return ""
+	}
+	ins, ok := parseInstruction(a.filebuffer.GetLine(line))
+	if !ok {
+		return ""
+	}
+	annotations := make([]string, 0, 2)
+	seen := make(map[string]bool)
+	for _, operand := range ins.operands {
+		for _, register := range registersin(operand) {
+			if seen[register] {
+				continue
+			}
+			seen[register] = true
+			if names := a.variablesin(line, canonicalregister(register, a.ve)); names != "" {
+				annotations = append(annotations, register+" = "+names)
+			}
+		}
+	}
+	return strings.Join(annotations, ", ")
+}
+
+// describevariables returns all variables living in registers at a line, with their types
+func (a *AssemblerFile) describevariables(line int) string {
+	descs := make([]string, 0, 4)
+	seen := make(map[string]bool)
+	for _, i := range a.regvarsat[line] {
+		v := a.regvars[i]
+		desc := fmt.Sprintf("%s %s in %s", v.typename, v.name, v.register)
+		if !seen[desc] {
+			seen[desc] = true
+			descs = append(descs, desc)
+		}
+	}
+	return strings.Join(descs, ", ")
+}
@@ -0,0 +1,42 @@
+package main
+
+/*
+	tests of the variable names from debug information
+*/
+
+import "testing"
+
+// the files in testdata are written by gcc 12 with -O2 -g -S -fno-asynchronous-unwind-tables
+// -gno-variable-location-views from
+//
+//	double g(double);
+//	double f(int n, double a)
+//	{
+//		double s = a * n;
+//		return g(s) + s;
+//	}
+//
+// dwarf4.s with -gdwarf-4 in addition, views.s with -gvariable-location-views=incompat5
+// instead, which writes DW_LLE_GNU_view_pair entries into the location lists
+func TestVariables(t *testing.T) {
+	for _, name := range []string{"testdata/dwarf5.s", "testdata/dwarf4.s", "testdata/views.s"} {
+		a, err := NewAssemblerFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tests := []struct {
+			line, variables string
+		}{
+			{"cvtsi2sdl %edi, %xmm1", "%edi = n"},
+			{"mulsd %xmm0, %xmm1", "%xmm0 = a"},
+			{"movapd %xmm1, %xmm0", "%xmm1 = s, %xmm0 = a"},
+			{"movsd %xmm1, 8(%rsp)", "%xmm1 = s"},
+			{"addsd %xmm1, %xmm0", ""}, // s was reloaded from the stack after the call
+		}
+		for _, test := range tests {
+			if v := a.variablesof(linewith(t, a, test.line)); v != test.variables {
+				t.Errorf("%s: variables of %q = %q, want %q", name, test.line, v, test.variables)
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+/*
+	explanation of instructions
+
+	used by the bottom panel of the user interface and the
+	tooltips of the HTML export.
+*/
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var reexplain = regexp.MustCompile(`^\s+(.+?)[\[\s].+$`)
+var reexplainshort = regexp.MustCompile(`^\s+(.+?)$`) // for lines without spaces at end
+
+// explainmnemonic returns the mnemonic of an instruction line, or "" for lines not matching
+func explainmnemonic(line string) string {
+	m := reexplain.FindStringSubmatch(line)
+	if m == nil {
+		m = reexplainshort.FindStringSubmatch(line)
+		if m == nil {
+			return ""
+		}
+	}
+	return m[1]
+}
+
+// explanationVE returns description, suffixes and registers of a VE instruction line,
+// empty parts are left out, nil for lines not matching
+func explanationVE(ops *Opstable, line string) []string {
+	mnemonic := explainmnemonic(line)
+	if mnemonic == "" {
+		return nil
+	}
+	lines := make([]string, 0, 3)
+	// FIXME what about . in first position?
+	if e := ops.lookup(mnemonic); e != "" {
+		lines = append(lines, e)
+	}
+	// explain suffixes
+	tokens := strings.Fields(line)
+	found := make([]string, 0, 4)
+	for suffix := range suffixes {
+		if strings.Index(tokens[0]+".", suffix+".") >= 0 {
+			found = append(found, suffix+":"+suffixes[suffix])
+		}
+	}
+	if len(found) > 0 {
+		sort.Strings(found)
+		lines = append(lines, strings.Join(found, ", "))
+	}
+	// explain registers
+	found = found[:0]
+	for register := range registers {
+		if strings.Index(line, register) >= 0 {
+			found = append(found, register+":"+registers[register])
+		}
+	}
+	if len(found) > 0 {
+		sort.Strings(found)
+		lines = append(lines, strings.Join(found, ", "))
+	}
+	return lines
+}
+
+// explanationX86 returns the description of an x86 instruction line, false for lines not matching
+func explanationX86(line string) (string, bool) {
+	m := explainmnemonic(line)
+	if m == "" {
+		return "", false
+	}
+	e, ok := x86ops[m]
+	if ok {
+		return m + " = " + e, true
+	}
+	if m[0] == 'v' && len(m) > 1 { // see if we find it without the v
+		if e, ok := x86ops[m[1:]]; ok {
+			return m + " = " + e, true
+		}
+		// see if we find it without first and last character
+		if e, ok := x86ops[m[1:len(m)-1]]; ok {
+			return m + " = " + e, true
+		}
+	} else if e, ok := x86ops[m[:len(m)-1]]; ok { // see if we find it without last character
+		return m + " = " + e, true
+	}
+	return "", true
+}
@@ -0,0 +1,200 @@
+package main
+
+/*
+	static HTML export
+
+		veass export --html out/ foo.s
+
+	writes out/index.html, a self-contained page with the assembly,
+	colored like in the user interface, next to the source files
+	found through filenametable. assembly lines link to the source
+	line they were generated for, source lines link to the first
+	assembly line generated for them, instructions have their
+	explanation and lint findings as tooltip.
+*/
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// colors of the color pairs of the user interface
+const exportstyle = `
+body { margin: 0; background: #000; color: #fff; font-family: monospace; }
+h1 { font-size: 1em; margin: 0; padding: 4px; background: #fff; color: #000; }
+h2 { font-size: 1em; margin: 8px 0 0 0; color: #ff0; }
+#main { display: flex; height: calc(100vh - 2em); }
+#asm, #src { overflow: auto; flex: 1; padding: 4px; }
+#src { border-left: 1px solid #888; }
+pre { margin: 0; }
+a { color: inherit; text-decoration: none; }
+a:hover { text-decoration: underline; }
+.n { color: #888; }
+.c1 { color: #fff; } .c3 { color: #55f; } .c4 { color: #f55; } .c5 { color: #5ff; }
+.c6 { color: #f5f; } .c8 { color: #ff5; }
+.lint { color: #f55; font-weight: bold; }
+.code { color: #5f5; }
+:target { background: #ff5; color: #00f; }
+`
+
+// sourcesections collects the readable source files, returns the section of each fileid (-1 if none)
+func (a *AssemblerFile) sourcesections() ([]string, []int) {
+	files := make([]string, 0, len(a.filenametable))
+	sections := make([]int, len(a.filenametable))
+	seen := make(map[string]int)
+	for id, name := range a.filenametable {
+		sections[id] = -1
+		if name == "" {
+			continue
+		}
+		abs, err := filepath.Abs(name)
+		if err != nil {
+			abs = name
+		}
+		if s, ok := seen[abs]; ok {
+			sections[id] = s
+			continue
+		}
+		if _, err := os.Stat(name); err != nil {
+			continue
+		}
+		seen[abs] = len(files)
+		sections[id] = len(files)
+		files = append(files, name)
+	}
+	return files, sections
+}
+
+// tooltip returns explanation and lint findings of an assembler line
+func (a *AssemblerFile) tooltip(ops *Opstable, linenr int) string {
+	line := a.filebuffer.GetLine(linenr)
+	parts := make([]string, 0, 4)
+	if _, ok := parseInstruction(line); ok {
+		if a.ve {
+			parts = append(parts, explanationVE(ops, line)...)
+		} else if e, _ := explanationX86(line); e != "" {
+			parts = append(parts, e)
+		}
+	}
+	for _, i := range a.findingsat[linenr] {
+		parts = append(parts, "! "+a.findings[i].message)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// exporthtml writes the HTML page for a file
+func (a *AssemblerFile) exporthtml(w io.Writer, title string) error {
+	model := NewAssemblerModel(a)
+	ops := NewOpstableVE()
+	files, sections := a.sourcesections()
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>%s</style>\n</head>\n<body>\n",
+		html.EscapeString(title), exportstyle)
+	fmt.Fprintf(bw, "<h1>%s</h1>\n<div id=\"main\">\n<div id=\"asm\"><pre>", html.EscapeString(title))
+
+	for l := 1; l < len(a.index); l++ {
+		line := a.filebuffer.GetLine(l)
+		number := fmt.Sprintf("%6d", l)
+		if loc := a.index[l].loc; loc.linenr > 0 && loc.fileid < len(sections) && sections[loc.fileid] != -1 {
+			number = fmt.Sprintf("<a href=\"#s%d-%d\" title=\"%s:%d\">%6d</a>", sections[loc.fileid], loc.linenr,
+				html.EscapeString(filepath.Base(files[sections[loc.fileid]])), loc.linenr, l)
+		}
+		mark := " "
+		if _, ok := a.findingsat[l]; ok {
+			mark = "<span class=\"lint\">!</span>"
+		}
+		color := int16(1)
+		if line != "" {
+			_, color, _ = model.GetCell(0, l)
+		}
+		tip := ""
+		if t := a.tooltip(ops, l); t != "" {
+			tip = fmt.Sprintf(" title=\"%s\"", html.EscapeString(t))
+		}
+		fmt.Fprintf(bw, "<span id=\"a%d\"><span class=\"n\">%s</span>%s<span class=\"c%d\"%s>%s</span></span>\n",
+			l, number, mark, color, tip, html.EscapeString(demangleline(line)))
+	}
+	fmt.Fprint(bw, "</pre></div>\n<div id=\"src\">\n")
+
+	// assembler lines for each source line
+	generated := make([]map[int][]int, len(files))
+	for i := range generated {
+		generated[i] = make(map[int][]int)
+	}
+	for loc, lines := range a.loctable {
+		if loc.fileid < len(sections) && sections[loc.fileid] != -1 {
+			generated[sections[loc.fileid]][loc.linenr] = append(generated[sections[loc.fileid]][loc.linenr], lines...)
+		}
+	}
+
+	for s, name := range files {
+		content, err := ioutil.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(bw, "<h2>%s</h2>\n<pre>", html.EscapeString(name))
+		for i, line := range strings.Split(strings.TrimSuffix(string(content), "\n"), "\n") {
+			linenr := i + 1
+			line = html.EscapeString(expandtabs(line))
+			if lines, ok := generated[s][linenr]; ok {
+				sort.Ints(lines)
+				refs := make([]string, len(lines))
+				for j, l := range lines {
+					refs[j] = fmt.Sprint(l)
+				}
+				fmt.Fprintf(bw, "<span id=\"s%d-%d\"><a class=\"code\" href=\"#a%d\" title=\"assembler lines %s\">%6d</a>  %s</span>\n",
+					s, linenr, lines[0], strings.Join(refs, ", "), linenr, line)
+			} else {
+				fmt.Fprintf(bw, "<span id=\"s%d-%d\"><span class=\"n\">%6d</span>  %s</span>\n", s, linenr, linenr, line)
+			}
+		}
+		fmt.Fprint(bw, "</pre>\n")
+	}
+	fmt.Fprint(bw, "</div>\n</div>\n</body>\n</html>\n")
+	return bw.Flush()
+}
+
+// runexport implements the export command, returns the exit code
+func runexport(args []string, outdir string) int {
+	if len(args) != 1 || outdir == "" {
+		fmt.Println("usage: veass export --html <outdir> <file.s>")
+		return 2
+	}
+	progress = ioutil.Discard
+
+	a, err := NewAssemblerFile(args[0])
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	a.lint(lintrules)
+
+	if err := os.MkdirAll(outdir, 0755); err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	filename := filepath.Join(outdir, "index.html")
+	f, err := os.Create(filename)
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	err = a.exporthtml(f, filepath.Base(args[0]))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	fmt.Println("written", filename)
+	return 0
+}
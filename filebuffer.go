@@ -64,7 +64,7 @@ func (f *FileBuffer) Addline(linenr int, line string) {
 		// some progress bar every 50K lines for large files
 		// guess nobody will ever notice :-)
 		if f.appendin%50 == 0 {
-			fmt.Print(".")
+			fmt.Fprint(progress, ".")
 		}
 	}
 
@@ -88,6 +88,11 @@ func (f *FileBuffer) GetLine(linenr int) string {
 	return (*lb).lines[linenr-(*lb).firstline]
 }
 
+// GetNrLines returns the number of the last line added
+func (f *FileBuffer) GetNrLines() int {
+	return f.lineblocks[f.appendin].lastline
+}
+
 // expandtabs replaces tab with spaces, we assume tabs of width 8 here
 func expandtabsSlow(line string) string {
 	var result string
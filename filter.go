@@ -0,0 +1,236 @@
+package main
+
+/*
+	filtered views of the top panel
+
+	filters hide lines of the assembler file: debug sections,
+	CFI directives, all directives, local labels no code or data
+	refers to, and functions of libraries (C++ standard library
+	templates, code from system headers). hidden lines get no row
+	in the top panel (see view.go), so navigation, marks and search
+	skip them, while the status bar still shows real line numbers.
+*/
+
+import (
+	"regexp"
+	"strings"
+)
+
+// filters
+const (
+	filterDebug = iota
+	filterCFI
+	filterDirectives
+	filterLabels
+	filterLibrary
+	nrfilters
+)
+
+var filternames = [nrfilters]string{"debug sections", "CFI directives", "all directives", "unused labels", "library functions"}
+
+// prefixes of sources of library code and of demangled names of library functions
+var librarypaths = []string{"/usr/include/", "/usr/lib/", "/usr/local/include/", "/opt/nec/ve/"}
+var librarynames = []string{"std::", "__gnu_cxx::", "__cxxabiv1::", "__gnu_debug::"}
+
+var resymbolref = regexp.MustCompile(`[A-Za-z0-9_.$@]+`)
+
+// hidden returns the lines hidden by a filter, indexed by line number, computed once per file
+func (a *AssemblerFile) hidden(filter int) []bool {
+	if a.filtered[filter] != nil {
+		return a.filtered[filter]
+	}
+	hidden := make([]bool, len(a.index))
+	switch filter {
+	case filterDebug:
+		debug := a.debuglines()
+		copy(hidden, debug)
+	case filterCFI:
+		for l := 1; l < len(a.index); l++ {
+			hidden[l] = strings.HasPrefix(strings.TrimSpace(a.filebuffer.GetLine(l)), ".cfi_")
+		}
+	case filterDirectives:
+		for l := 1; l < len(a.index); l++ {
+			line := a.filebuffer.GetLine(l)
+			hidden[l] = strings.HasPrefix(strings.TrimSpace(line), ".") && labelof(line) == ""
+		}
+	case filterLabels:
+		a.unusedlabels(hidden)
+	case filterLibrary:
+		for _, f := range a.functions {
+			if a.islibrary(f) {
+				for l := f.start; l <= f.end; l++ {
+					hidden[l] = true
+				}
+			}
+		}
+	}
+	a.filtered[filter] = hidden
+	return hidden
+}
+
+// debuglines returns the lines in .debug sections, including the .section lines switching to them
+func (a *AssemblerFile) debuglines() []bool {
+	debug := make([]bool, len(a.index))
+	for l, section := range a.sectionlines() {
+		debug[l] = strings.HasPrefix(section, ".debug")
+	}
+	return debug
+}
+
+// sectionlines returns the section of each line, lines switching sections belong to the new section,
+// computed once per file
+func (a *AssemblerFile) sectionlines() []string {
+	if a.sections != nil {
+		return a.sections
+	}
+	sections := make([]string, len(a.index))
+	section, previous := "", ""
+	stack := make([]string, 0, 4)
+	for l := 1; l < len(a.index); l++ {
+		fields := strings.Fields(strings.Replace(a.filebuffer.GetLine(l), ",", " ", -1))
+		if len(fields) > 0 {
+			switch fields[0] {
+			case ".section", ".pushsection":
+				if fields[0] == ".pushsection" {
+					stack = append(stack, section)
+				}
+				previous, section = section, ""
+				if len(fields) > 1 {
+					section = fields[1]
+				}
+			case ".popsection":
+				if len(stack) > 0 {
+					previous, section = section, stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+				}
+			case ".previous":
+				previous, section = section, previous
+			case ".text", ".data", ".bss":
+				previous, section = section, fields[0]
+			}
+		}
+		sections[l] = section
+	}
+	a.sections = sections
+	return sections
+}
+
+// unusedlabels hides local labels no line outside of debug sections refers to
+func (a *AssemblerFile) unusedlabels(hidden []bool) {
+	debug := a.debuglines()
+	used := make(map[string]bool)
+	for l := 1; l < len(a.index); l++ {
+		if debug[l] {
+			continue
+		}
+		line := a.filebuffer.GetLine(l)
+		if label := labelof(line); label != "" {
+			line = line[len(label)+1:]
+		}
+		if c := strings.Index(line, "#"); c >= 0 {
+			line = line[:c]
+		}
+		for _, ref := range resymbolref.FindAllString(line, -1) {
+			used[ref] = true
+		}
+	}
+	for name, l := range a.labels {
+		if name[0] == '.' && !used[name] {
+			hidden[l] = true
+		}
+	}
+}
+
+// islibrary tells if a function is library code, by its name or the source it was generated from
+func (a *AssemblerFile) islibrary(f Function) bool {
+	name := qualifiedname(demangle(f.name))
+	for _, prefix := range librarynames {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	for l := f.start; l <= f.end; l++ {
+		if loc := a.index[l].loc; loc.linenr > 0 && loc.fileid < len(a.filenametable) {
+			for _, path := range librarypaths {
+				if strings.HasPrefix(a.filenametable[loc.fileid], path) {
+					return true
+				}
+			}
+			return false // first location decides
+		}
+	}
+	return false
+}
+
+// qualifiedname returns a demangled function name without return type and parameters
+func qualifiedname(name string) string {
+	depth := 0
+	start := 0
+	for i := 0; i < len(name); i++ {
+		switch name[i] {
+		case '<':
+			depth++
+		case '>':
+			depth--
+		case ' ':
+			if depth == 0 {
+				start = i + 1 // return type of templates in front
+			}
+		case '(':
+			if depth == 0 && i > start {
+				return name[start:i]
+			}
+		}
+	}
+	return name[start:]
+}
+
+// hiddentop returns the lines hidden by the filters switched on, nil if none is on
+func (t *TuiT) hiddentop() []bool {
+	var hidden []bool
+	for filter, on := range t.filters {
+		if !on {
+			continue
+		}
+		if hidden == nil {
+			hidden = make([]bool, len(assemblerfile.index))
+		}
+		for l, h := range assemblerfile.hidden(filter) {
+			hidden[l] = hidden[l] || h
+		}
+	}
+	return hidden
+}
+
+// visibletop tells if an assembler line has a row in the top panel
+func (t *TuiT) visibletop(line int) bool {
+	if t.toprows == nil {
+		return true
+	}
+	row := t.toprows[t.rowtop(line)]
+	return row.line == line && !row.header
+}
+
+// filtermenu shows the filters, <enter> toggles the selected one, keeps the cursor line
+func (t *TuiT) filtermenu() {
+	line := t.cursortop()
+	selected := 0
+	for {
+		entries := make([]ListEntry, nrfilters)
+		for f := range entries {
+			check := "[ ]"
+			if t.filters[f] {
+				check = "[x]"
+			}
+			entries[f] = ListEntry{check + " " + filternames[f], 0}
+		}
+		selected = t.showlistat("filters: <enter> toggles, <q> closes", entries, selected)
+		if selected == -1 {
+			break
+		}
+		t.filters[selected] = !t.filters[selected]
+	}
+	t.buildrowstop()
+	t.top.Erase()
+	t.showlinetop(line)
+}
@@ -0,0 +1,216 @@
+package main
+
+/*
+	symbol finder
+
+	a popup listing all global symbols, functions declared with
+	.type and local labels, functions with their instruction count
+	from the function table. typing filters the list fuzzily: the
+	characters of the pattern have to appear in the name in order,
+	matches at the start of words and consecutive matches rank
+	higher. demangled and raw names are both matched.
+*/
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	gc "github.com/rthornton128/goncurses"
+)
+
+// kinds of symbols in the finder
+const (
+	symbolGlobal = iota
+	symbolFunction
+	symbolLabel
+	symbolLocal
+)
+
+var symbolkinds = []string{"global", "func", "label", "local"}
+
+// Symbol is an entry of the symbol finder
+type Symbol struct {
+	name         string // raw name
+	line         int    // line of the label
+	kind         int
+	instructions int // instructions of functions, -1 for other labels
+}
+
+// symbols returns all labels of the file with their kind and instruction count, ordered by line
+func (a *AssemblerFile) symbols() []Symbol {
+	functions := make(map[string]Function)
+	for _, f := range a.functions {
+		functions[f.name] = f
+	}
+	symbols := make([]Symbol, 0, len(a.labels))
+	for name, line := range a.labels {
+		s := Symbol{name: name, line: line, kind: symbolLabel, instructions: -1}
+		if f, ok := functions[name]; ok {
+			s.kind = symbolFunction
+			if f.binding != "local" {
+				s.kind = symbolGlobal
+			}
+			s.instructions = f.instructions
+		} else if name[0] == '.' {
+			s.kind = symbolLocal
+		}
+		symbols = append(symbols, s)
+	}
+	sort.Slice(symbols, func(i, j int) bool { return symbols[i].line < symbols[j].line })
+	return symbols
+}
+
+// fuzzymatch checks if all characters of pattern appear in text in order, higher scores are better matches
+func fuzzymatch(pattern, text string) (int, bool) {
+	pattern = strings.ToLower(pattern)
+	lower := strings.ToLower(text)
+	score := 0
+	last := -2
+	p := 0
+	for i := 0; i < len(lower) && p < len(pattern); i++ {
+		if lower[i] != pattern[p] {
+			continue
+		}
+		score++
+		if i == last+1 {
+			score += 4 // consecutive
+		}
+		if i == 0 || !isidentchar(lower[i-1]) || text[i-1] >= 'a' && text[i-1] <= 'z' && text[i] >= 'A' && text[i] <= 'Z' {
+			score += 6 // start of a word
+		}
+		last = i
+		p++
+	}
+	if p < len(pattern) {
+		return 0, false
+	}
+	if len(pattern) == len(lower) {
+		score += 20 // exact
+	}
+	return score, true
+}
+
+// format returns the text of a symbol in the finder
+func (s Symbol) format() string {
+	count := "" // empty for labels which are not functions
+	if s.instructions >= 0 {
+		count = fmt.Sprintf("%d", s.instructions)
+	}
+	return fmt.Sprintf("%-6s %7s %7d  %s", symbolkinds[s.kind], count, s.line, symbolname(s.name))
+}
+
+// filtersymbols returns the symbols matching pattern, best matches first, all symbols for an empty pattern
+func filtersymbols(symbols []Symbol, pattern string) []Symbol {
+	if pattern == "" {
+		return symbols
+	}
+	type match struct {
+		symbol Symbol
+		score  int
+	}
+	matches := make([]match, 0, len(symbols))
+	for _, s := range symbols {
+		score, ok := fuzzymatch(pattern, symbolname(s.name))
+		if raw, rawok := fuzzymatch(pattern, s.name); rawok && (!ok || raw > score) {
+			score, ok = raw, true
+		}
+		if ok {
+			// shorter names are closer matches, local labels are less interesting
+			score = score*4 - len(s.name)/8 - s.kind
+			matches = append(matches, match{s, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	filtered := make([]Symbol, len(matches))
+	for i, m := range matches {
+		filtered[i] = m.symbol
+	}
+	return filtered
+}
+
+// symbolfinder shows the finder popup and jumps to the selected symbol
+func (t *TuiT) symbolfinder() {
+	win, err := gc.NewWindow(t.toplines, t.maxx, 0, 0)
+	if err != nil {
+		return
+	}
+	win.Keypad(true)
+	defer func() {
+		win.Erase()
+		win.NoutRefresh()
+		win.Delete()
+		t.top.Touch()
+		t.Refreshtopall()
+		t.drawother()
+	}()
+
+	symbols := assemblerfile.symbols()
+	pattern := ""
+	filtered := symbols
+	rows := t.toplines - 2 // title and input line
+	first := 0
+	cursor := 0
+
+	for {
+		win.Erase()
+		win.AttrOn(gc.A_REVERSE)
+		win.MovePrint(0, 0, fmt.Sprintf("%-*s", t.maxx, fmt.Sprintf(" symbols: kind   instr    line  name  (%d/%d)", len(filtered), len(symbols))))
+		win.AttrOff(gc.A_REVERSE)
+		win.ColorOn(1)
+		win.MovePrint(1, 0, "find: "+pattern)
+		for y := 0; y < rows && first+y < len(filtered); y++ {
+			text := filtered[first+y].format()
+			if len(text) > t.maxx {
+				text = text[:t.maxx]
+			}
+			if first+y == cursor {
+				win.ColorOn(2)
+			} else {
+				win.ColorOn(1)
+			}
+			win.MovePrint(y+2, 0, fmt.Sprintf("%-*s", t.maxx, text))
+		}
+		win.ColorOn(1)
+		win.Move(1, 6+len(pattern))
+		win.NoutRefresh()
+		gc.Update()
+
+		key := win.GetChar()
+		switch key {
+		case gc.KEY_DOWN:
+			cursor = mini(cursor+1, len(filtered)-1)
+		case gc.KEY_UP:
+			cursor = maxi(cursor-1, 0)
+		case gc.KEY_PAGEDOWN:
+			cursor = mini(cursor+rows, len(filtered)-1)
+		case gc.KEY_PAGEUP:
+			cursor = maxi(cursor-rows, 0)
+		case gc.KEY_RETURN:
+			if len(filtered) > 0 {
+				t.showlinetop(filtered[cursor].line)
+			}
+			return
+		case gc.KEY_ESC:
+			return
+		case gc.KEY_BACKSPACE, 127, 8:
+			if pattern != "" {
+				pattern = pattern[:len(pattern)-1]
+				filtered = filtersymbols(symbols, pattern)
+				cursor, first = 0, 0
+			}
+		default:
+			if key > ' ' && key < 127 {
+				pattern += string(rune(key))
+				filtered = filtersymbols(symbols, pattern)
+				cursor, first = 0, 0
+			}
+		}
+		cursor = maxi(cursor, 0)
+		if cursor < first {
+			first = cursor
+		} else if cursor >= first+rows {
+			first = cursor - rows + 1
+		}
+	}
+}
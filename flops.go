@@ -0,0 +1,233 @@
+package main
+
+/*
+	floating point operations and memory traffic per loop iteration
+
+	flops and bytes loaded or stored are counted for the lines of
+	a loop which are not inside of a nested loop, so this is
+	the work of one iteration of the loop body, without inner loops.
+	fused multiply add counts as two operations.
+	for VE, vector instructions are scaled with the vector length
+	where it is known, an upper bound is used as estimate and 256
+	elements are assumed if nothing is known.
+	the type of VE operations comes from the suffixes (.d, .s), for
+	x86 from the mnemonic (ps, pd, ss, sd) and the register class.
+	stack slot accesses are spills, not data traffic, and are ignored.
+
+	arithmetic intensity = flops / bytes tells memory bound loops
+	(low intensity) from compute bound loops (high intensity),
+	the report calls loops below one flop per byte memory bound.
+*/
+
+import (
+	"fmt"
+	"strings"
+)
+
+const vemaxvl = 256 // maximum vector length of VE
+
+// Traffic is the work of one loop iteration
+type Traffic struct {
+	flops     int64
+	loaded    int64 // bytes
+	stored    int64 // bytes
+	vlguessed bool  // vector length was not known for some instruction
+}
+
+// intensity returns flops per byte, 0 if there is no memory traffic
+func (t Traffic) intensity() float64 {
+	if t.loaded+t.stored == 0 {
+		return 0
+	}
+	return float64(t.flops) / float64(t.loaded+t.stored)
+}
+
+// veflops are the floating point operations per element of VE instructions
+var veflops = map[string]int64{
+	"fadd": 1, "fsub": 1, "fmul": 1, "fdiv": 1, "fsqrt": 1,
+	"vfadd": 1, "vfsub": 1, "vfmul": 1, "vfdiv": 1, "vfsqrt": 1, "vfsum": 1,
+	"vfmad": 2, "vfmsb": 2, "vfnmad": 2, "vfnmsb": 2,
+	"vfia": 1, "vfis": 1, "vfim": 1, "vfiam": 2, "vfism": 2, "vfima": 2, "vfims": 2,
+}
+
+// vebytes are the bytes per element of VE loads and stores
+var vebytes = map[string]int64{
+	"ld": 8, "ldu": 4, "ldl": 4, "ld2b": 2, "ld1b": 1, "dld": 8, "dldu": 4, "dldl": 4,
+	"st": 8, "stu": 4, "stl": 4, "st2b": 2, "st1b": 1,
+	"vld": 8, "vldu": 4, "vldl": 4, "vld2d": 8, "vldu2d": 4, "vldl2d": 4,
+	"vst": 8, "vstu": 4, "vstl": 4, "vst2d": 8, "vstu2d": 4, "vstl2d": 4,
+	"vgt": 8, "vgtu": 4, "vgtl": 4, "vsc": 8, "vscu": 4, "vscl": 4,
+}
+
+// isfloatingpoint checks the suffixes of a VE mnemonic for a floating point type
+func isfloatingpoint(mnemonic string) bool {
+	for _, s := range strings.Split(mnemonic, ".")[1:] {
+		if strings.Contains(suffixes["."+s], "floating point") {
+			return true
+		}
+	}
+	return false
+}
+
+// trafficVE returns flops and bytes of a VE instruction in line
+func (a *AssemblerFile) trafficVE(ins Instruction, line int) Traffic {
+	var t Traffic
+	base := ins.basemnemonic()
+	packed := int64(1)
+	if strings.HasPrefix(base, "pv") {
+		// packed instructions work on two 32 bit values per element
+		base = base[1:]
+		packed = 2
+	}
+	elements := int64(1)
+	if isvector(ins) {
+		elements = vemaxvl
+		if v, ok := a.vlof(line); ok && (v.state == vlConst || v.state == vlSymbolic && v.value > 0) {
+			elements = v.value
+		} else {
+			t.vlguessed = true
+		}
+	}
+	if f, ok := veflops[base]; ok && (packed == 2 || isfloatingpoint(ins.mnemonic)) {
+		t.flops = f * elements * packed
+	}
+	if _, stack := a.stackaccess[line]; !stack {
+		if b, ok := vebytes[base]; ok {
+			switch classifyVE(ins) {
+			case classStore, classVectorStore, classScatter:
+				t.stored = b * elements
+			default:
+				t.loaded = b * elements
+			}
+		}
+	}
+	return t
+}
+
+// x86 floating point arithmetic, without v prefix and type suffix
+var x86flops = map[string]int64{
+	"add": 1, "sub": 1, "mul": 1, "div": 1, "sqrt": 1, "hadd": 1, "hsub": 1, "addsub": 1,
+	"fmadd": 2, "fmsub": 2, "fnmadd": 2, "fnmsub": 2, "fmaddsub": 2, "fmsubadd": 2,
+}
+
+// x86elements returns number and size of elements of a floating point mnemonic, 0 for others
+func x86elements(mnemonic string, width int) (int64, int64) {
+	var size int64
+	switch {
+	case strings.HasSuffix(mnemonic, "ps") || strings.HasSuffix(mnemonic, "ss"):
+		size = 4
+	case strings.HasSuffix(mnemonic, "pd") || strings.HasSuffix(mnemonic, "sd"):
+		size = 8
+	default:
+		return 0, 0
+	}
+	switch width {
+	case width128:
+		return 16 / size, size
+	case width256:
+		return 32 / size, size
+	case width512:
+		return 64 / size, size
+	}
+	return 1, size
+}
+
+// trafficX86 returns flops and bytes of an x86 instruction in line
+func (a *AssemblerFile) trafficX86(ins Instruction, line int) Traffic {
+	var t Traffic
+	m := ins.mnemonic
+	width := simdwidth(ins)
+	elements, size := x86elements(m, width)
+	if elements > 0 {
+		op := strings.TrimRight(strings.TrimPrefix(m[:len(m)-2], "v"), "0123456789")
+		if f, ok := x86flops[op]; ok {
+			t.flops = f * elements
+		}
+	}
+	if _, stack := a.stackaccess[line]; stack {
+		return t
+	}
+	load, store := memoryaccess(ins)
+	if !load && !store {
+		return t
+	}
+	var bytes int64
+	switch {
+	case strings.Contains(m, "broadcast") && size > 0:
+		bytes = size
+	case width == width128 || width == width256 || width == width512:
+		bytes = map[int]int64{width128: 16, width256: 32, width512: 64}[width]
+		if strings.Contains(m, "gather") || strings.Contains(m, "scatter") {
+			bytes = elements * size
+		}
+	case size > 0:
+		bytes = size
+	default:
+		bytes = map[byte]int64{'b': 1, 'w': 2, 'l': 4, 'q': 8}[m[len(m)-1]]
+		if bytes == 0 {
+			bytes = 8
+		}
+	}
+	if store {
+		t.stored = bytes
+	}
+	// a store by anything but a move is a read-modify-write like addl $1,(%rax),
+	// compares only read their memory operand and report no store
+	mov := strings.HasPrefix(m, "mov") || strings.HasPrefix(m, "vmov")
+	if load || store && !mov {
+		t.loaded = bytes
+	}
+	return t
+}
+
+// looptraffic sums the work of one iteration of a loop, without nested loops
+func (a *AssemblerFile) looptraffic(li int) Traffic {
+	var sum Traffic
+	loop := a.loops[li]
+	for l := loop.head; l <= loop.latch; l++ {
+		if a.loopof(l) != li {
+			continue
+		}
+		ins, ok := parseInstruction(a.filebuffer.GetLine(l))
+		if !ok {
+			continue
+		}
+		var t Traffic
+		if a.ve {
+			t = a.trafficVE(ins, l)
+		} else {
+			t = a.trafficX86(ins, l)
+		}
+		sum.flops += t.flops
+		sum.loaded += t.loaded
+		sum.stored += t.stored
+		sum.vlguessed = sum.vlguessed || t.vlguessed
+	}
+	return sum
+}
+
+// trafficreport returns one list entry per loop with flops, bytes and intensity
+func (a *AssemblerFile) trafficreport() []ListEntry {
+	entries := make([]ListEntry, 0, len(a.loops))
+	for li, loop := range a.loops {
+		t := a.looptraffic(li)
+		flags := ""
+		if !loop.inner {
+			flags += "  (without inner loops)"
+		}
+		if t.vlguessed {
+			flags += "  (VL unknown, 256 assumed)"
+		}
+		bound := "-"
+		if t.loaded+t.stored > 0 {
+			bound = "memory"
+			if t.intensity() >= 1.0 {
+				bound = "compute"
+			}
+		}
+		name := strings.Repeat(" ", loop.depth-1) + a.loopname(li)
+		entries = append(entries, ListEntry{fmt.Sprintf("%7d %7d %7d %7.2f %-7s  %-20s %s%s",
+			t.flops, t.loaded, t.stored, t.intensity(), bound, name, symbolname(a.functions[loop.function].name), flags), loop.head})
+	}
+	return entries
+}
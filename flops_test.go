@@ -0,0 +1,52 @@
+package main
+
+/*
+	tests of the flop and memory traffic counts
+*/
+
+import "testing"
+
+func TestTrafficX86(t *testing.T) {
+	tests := []struct {
+		line                  string
+		flops, loaded, stored int64
+	}{
+		{"\taddsd\t(%rax), %xmm0", 1, 8, 0},
+		{"\tmovsd\t%xmm0, (%rax)", 0, 0, 8},
+		{"\tmovsd\t(%rax), %xmm0", 0, 8, 0},
+		{"\tvfmadd231pd\t(%rdi,%rax), %ymm1, %ymm0", 8, 32, 0},
+		{"\tvmovupd\t%ymm0, (%rsi,%rax)", 0, 0, 32},
+		{"\tmulps\t%xmm1, %xmm0", 4, 0, 0},
+		{"\tvbroadcastsd\t(%rax), %ymm0", 0, 8, 0},
+		{"\taddl\t$1, (%rax)", 0, 4, 4},
+		{"\tcmpq\t$0, (%rax)", 0, 8, 0},
+		{"\ttestl\t%ebx, 8(%rax)", 0, 4, 0},
+		{"\tucomisd\t(%rax), %xmm0", 0, 8, 0},
+		{"\tmovq\t%rbx, 8(%rax)", 0, 0, 8},
+		{"\tleaq\t8(%rax), %rbx", 0, 0, 0},
+	}
+	a := &AssemblerFile{}
+	for _, test := range tests {
+		ins, _ := parseInstruction(test.line)
+		if tr := a.trafficX86(ins, 0); tr.flops != test.flops || tr.loaded != test.loaded || tr.stored != test.stored {
+			t.Errorf("traffic of %q = %d flops, %d loaded, %d stored, want %d, %d, %d",
+				test.line, tr.flops, tr.loaded, tr.stored, test.flops, test.loaded, test.stored)
+		}
+	}
+}
+
+func TestLoopTrafficVE(t *testing.T) {
+	a := testfile(t, vesample)
+	tr := a.looptraffic(0)
+	// 256 elements assumed for VL <= 256: vfmad.d and vfadd.d,
+	// loads by vld, vldl.sx and vgt, stores by vst and vsc, the stack accesses do not count
+	if tr.flops != 3*256 || tr.loaded != (8+4+8)*256 || tr.stored != (8+8)*256 {
+		t.Errorf("%d flops, %d loaded, %d stored, want %d, %d, %d", tr.flops, tr.loaded, tr.stored, 3*256, 20*256, 16*256)
+	}
+	if tr.vlguessed {
+		t.Errorf("VL guessed, the upper bound is known")
+	}
+	if i := tr.intensity(); i != 768.0/9216.0 {
+		t.Errorf("intensity %v, want %v", i, 768.0/9216.0)
+	}
+}
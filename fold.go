@@ -0,0 +1,194 @@
+package main
+
+/*
+	code folding in the top panel
+
+	a function, a loop or a section can be folded into one summary
+	row showing its name, instruction count and source range.
+	folds are a layer of the rows of the top panel (see view.go),
+	the lines of a fold get no row, so navigation and search skip
+	them like lines hidden by filters.
+*/
+
+import (
+	"fmt"
+	"path/filepath"
+
+	gc "github.com/rthornton128/goncurses"
+)
+
+// kinds of folds
+const (
+	foldFunction = iota
+	foldLoop
+	foldSection
+)
+
+var foldkinds = []string{"function", "loop", "section"}
+
+// Fold is a folded range of lines in the top panel, stored by its first line
+type Fold struct {
+	end     int // last folded line
+	kind    int
+	name    string
+	summary string // text of the summary row
+}
+
+// sectionrange returns first and last line and name of the section containing line
+func (a *AssemblerFile) sectionrange(line int) (int, int, string) {
+	sections := a.sectionlines()
+	if line < 1 || line >= len(sections) {
+		return 0, 0, ""
+	}
+	start, end := line, line
+	for start > 1 && sections[start-1] == sections[line] {
+		start--
+	}
+	for end+1 < len(sections) && sections[end+1] == sections[line] {
+		end++
+	}
+	return start, end, sections[line]
+}
+
+// newfold returns the fold of the lines start to end with its summary
+func (a *AssemblerFile) newfold(start, end, kind int, name string) Fold {
+	instructions := 0
+	for l := start; l <= end; l++ {
+		if _, ok := parseInstruction(a.filebuffer.GetLine(l)); ok {
+			instructions++
+		}
+	}
+	text := fmt.Sprintf("[+] %s %s: %d instructions, lines %d-%d", foldkinds[kind], name, instructions, start, end)
+	if source, first, last := a.sourcerange(start, end); first > 0 {
+		text += fmt.Sprintf(", source %s:%d-%d", filepath.Base(source), first, last)
+	}
+	return Fold{end, kind, name, text}
+}
+
+// foldat returns the innermost loop, function or section containing line as fold, false if there is none
+func (t *TuiT) foldat(line int) (int, Fold, bool) {
+	if li := assemblerfile.loopof(line); li >= 0 {
+		loop := assemblerfile.loops[li]
+		return loop.head, assemblerfile.newfold(loop.head, loop.latch, foldLoop, assemblerfile.loopname(li)), true
+	}
+	if fi := assemblerfile.functionof(line); fi >= 0 {
+		f := assemblerfile.functions[fi]
+		return f.start, assemblerfile.newfold(f.start, f.end, foldFunction, symbolname(f.name)), true
+	}
+	if start, end, name := assemblerfile.sectionrange(line); name != "" {
+		return start, assemblerfile.newfold(start, end, foldSection, name), true
+	}
+	return 0, Fold{}, false
+}
+
+// enclosingfold returns the innermost loop, function or section containing the lines start to end
+// and larger than them as fold, false if there is none
+func (t *TuiT) enclosingfold(start, end int) (int, Fold, bool) {
+	inner := -1
+	for li, loop := range assemblerfile.loops {
+		if loop.head <= start && loop.latch >= end && (loop.head != start || loop.latch != end) &&
+			(inner < 0 || loop.latch-loop.head < assemblerfile.loops[inner].latch-assemblerfile.loops[inner].head) {
+			inner = li
+		}
+	}
+	if inner >= 0 {
+		loop := assemblerfile.loops[inner]
+		return loop.head, assemblerfile.newfold(loop.head, loop.latch, foldLoop, assemblerfile.loopname(inner)), true
+	}
+	if fi := assemblerfile.functionof(start); fi >= 0 {
+		f := assemblerfile.functions[fi]
+		if f.start <= start && f.end >= end && (f.start != start || f.end != end) {
+			return f.start, assemblerfile.newfold(f.start, f.end, foldFunction, symbolname(f.name)), true
+		}
+	}
+	if first, last, name := assemblerfile.sectionrange(start); name != "" && last >= end && (first != start || last != end) {
+		return first, assemblerfile.newfold(first, last, foldSection, name), true
+	}
+	return 0, Fold{}, false
+}
+
+// togglefold folds the innermost loop, function or section at the cursor, or unfolds the fold at the cursor
+func (t *TuiT) togglefold() {
+	row := t.viewrowtop(t.toptopline + t.topcursor)
+	if row.end > 0 {
+		delete(t.folds, row.line)
+		t.refold(row.line)
+		return
+	}
+	start, fold, ok := t.foldat(row.line)
+	if !ok {
+		return
+	}
+	if t.folds == nil {
+		t.folds = make(map[int]Fold)
+	}
+	t.folds[start] = fold
+	t.refold(start)
+}
+
+// foldouter folds the next range around the fold at the cursor, or around the innermost range
+// at the cursor, so loops, functions and sections can be folded from inside
+func (t *TuiT) foldouter() {
+	row := t.viewrowtop(t.toptopline + t.topcursor)
+	start, end := row.line, row.end
+	if row.end == 0 {
+		first, fold, ok := t.foldat(row.line)
+		if !ok {
+			return
+		}
+		start, end = first, fold.end
+	}
+	first, fold, ok := t.enclosingfold(start, end)
+	if !ok {
+		return
+	}
+	if t.folds == nil {
+		t.folds = make(map[int]Fold)
+	}
+	t.folds[first] = fold
+	t.refold(first)
+}
+
+// foldall folds all functions
+func (t *TuiT) foldall() {
+	line := t.cursortop()
+	t.folds = make(map[int]Fold)
+	for _, f := range assemblerfile.functions {
+		t.folds[f.start] = assemblerfile.newfold(f.start, f.end, foldFunction, symbolname(f.name))
+	}
+	if fi := assemblerfile.functionof(line); fi >= 0 {
+		line = assemblerfile.functions[fi].start
+	}
+	t.refold(line)
+}
+
+// unfoldall removes all folds
+func (t *TuiT) unfoldall() {
+	t.folds = nil
+	t.refold(t.cursortop())
+}
+
+// refold rebuilds the rows after folds changed and shows line
+func (t *TuiT) refold(line int) {
+	t.buildrowstop()
+	t.top.Erase()
+	t.showlinetop(line)
+}
+
+// drawfoldtop draws the summary of a fold in row y of the top panel, y in screen coordinates
+func (t *TuiT) drawfoldtop(y int, row viewrow, offset int) {
+	text := t.folds[row.line].summary
+	if len(text) > t.topwidth-offset {
+		text = text[:maxi(0, t.topwidth-offset)]
+	}
+	t.top.ColorOn(5)
+	if _, marked := t.topmarked[row.line]; marked {
+		t.top.ColorOn(2)
+	}
+	if y == t.topcursor {
+		t.top.AttrOn(gc.A_BOLD)
+	}
+	t.top.MovePrint(y, offset, text)
+	t.top.AttrOff(gc.A_BOLD)
+	t.top.ClearToEOL()
+}
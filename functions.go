@@ -0,0 +1,133 @@
+package main
+
+/*
+	functions of an assembler file
+
+	a function is the range of lines of one function symbol, it is
+	the unit most analysis results are summarized for.
+	function symbols are the labels declared with .type @function,
+	global labels without .type are taken as functions as well, for
+	hand written assembler. a function starts at its label and ends
+	with its .size directive, or with .cfi_endproc, or in front of
+	the next function. so static functions, inline functions and
+	outlined OpenMP bodies get their own range instead of being
+	attributed to the global symbol in front of them.
+*/
+
+import (
+	"sort"
+	"strings"
+)
+
+// Function is a range of lines belonging to one symbol
+type Function struct {
+	name         string
+	start, end   int    // first and last line in assembler file
+	binding      string // global, weak or local
+	size         string // expression of the .size directive, like .-name, "" if there is none
+	instructions int    // number of instruction lines
+	loops        []int  // indices into loops, ordered by head line
+	spills       int    // stores into stack slots
+	reloads      int    // loads from stack slots
+}
+
+// buildfunctions builds the function table from .type, .size, .globl, .cfi_endproc and labels,
+// and sets the symbol of each line in the index
+func (a *AssemblerFile) buildfunctions() {
+	types := make(map[string]string)    // symbol -> type like function or object
+	bindings := make(map[string]string) // symbol -> global or weak
+	sizes := make(map[string]int)       // symbol -> line of .size
+	sizeexprs := make(map[string]string)
+	labels := make(map[string]int) // first definition only
+	endprocs := make([]int, 0, 64) // lines of .cfi_endproc
+	for l := 1; l < len(a.index); l++ {
+		line := a.filebuffer.GetLine(l)
+		if len(line) > 0 && line[0] != ' ' && line[0] != '\t' && line[0] != '#' {
+			if label := labelof(line); label != "" {
+				if _, ok := labels[label]; !ok {
+					labels[label] = l
+				}
+			}
+			continue
+		}
+		fields := strings.Fields(strings.Replace(line, ",", " ", -1))
+		if len(fields) < 2 {
+			if len(fields) == 1 && fields[0] == ".cfi_endproc" {
+				endprocs = append(endprocs, l)
+			}
+			continue
+		}
+		switch fields[0] {
+		case ".globl", ".global":
+			for _, name := range fields[1:] {
+				bindings[name] = "global"
+			}
+		case ".weak":
+			for _, name := range fields[1:] {
+				bindings[name] = "weak"
+			}
+		case ".type":
+			if len(fields) > 2 {
+				types[fields[1]] = strings.TrimLeft(fields[2], "@%#")
+			}
+		case ".size":
+			sizes[fields[1]] = l
+			sizeexprs[fields[1]] = strings.TrimSpace(line[strings.Index(line, ",")+1:])
+		}
+	}
+
+	a.functions = make([]Function, 0, 64)
+	for name, line := range labels {
+		typ, typed := types[name]
+		_, bound := bindings[name]
+		if typ == "function" || !typed && bound {
+			binding := bindings[name]
+			if binding == "" {
+				binding = "local"
+			}
+			a.functions = append(a.functions, Function{name: name, start: line, binding: binding, size: sizeexprs[name]})
+		}
+	}
+	sort.Slice(a.functions, func(i, j int) bool { return a.functions[i].start < a.functions[j].start })
+
+	for i := range a.functions {
+		f := &a.functions[i]
+		limit := len(a.index) - 1 // last line the function can reach
+		if i+1 < len(a.functions) {
+			limit = a.functions[i+1].start - 1
+		}
+		f.end = limit
+		if end, ok := sizes[f.name]; ok && end > f.start && end <= limit {
+			f.end = end
+		} else if e := sort.SearchInts(endprocs, f.start); e < len(endprocs) && endprocs[e] <= limit {
+			f.end = endprocs[e]
+		}
+		for l := f.start; l <= f.end; l++ {
+			a.index[l].symbol = f.name
+			if _, ok := parseInstruction(a.filebuffer.GetLine(l)); ok {
+				f.instructions++
+			}
+		}
+	}
+}
+
+// functionlabel returns binding and kind of the function containing line for the status bar, like "global function", "" outside of functions
+func (a *AssemblerFile) functionlabel(line int) string {
+	fi := a.functionof(line)
+	if fi < 0 {
+		return ""
+	}
+	if a.functions[fi].binding == "local" {
+		return "static function"
+	}
+	return a.functions[fi].binding + " function"
+}
+
+// functionof returns the index of the function containing line, or -1
+func (a *AssemblerFile) functionof(line int) int {
+	i := sort.Search(len(a.functions), func(i int) bool { return a.functions[i].end >= line })
+	if i < len(a.functions) && a.functions[i].start <= line {
+		return i
+	}
+	return -1
+}
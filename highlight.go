@@ -0,0 +1,337 @@
+package main
+
+/*
+	syntax highlighting of source files
+
+	a small lexer for C, C++ and free and fixed form Fortran,
+	coloring keywords, comments, strings, preprocessor lines and
+	numbers. it works line by line, the only state carried from
+	one line to the next is being inside of a C block comment or
+	a continued preprocessor line.
+*/
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// source languages
+const (
+	langNone = iota
+	langC
+	langCPP
+	langFortranFree
+	langFortranFixed
+)
+
+// colors of the highlighting, see color pairs in tui.go
+const (
+	hlNormal       int16 = 1
+	hlComment      int16 = 3
+	hlPreprocessor int16 = 4
+	hlKeyword      int16 = 5
+	hlString       int16 = 6
+	hlNumber       int16 = 8
+	hlDirective    int16 = 15 // recognized compiler directives, see directive.go
+)
+
+// lexer states at the end of a line
+const (
+	hlStateNormal       = iota
+	hlStateComment      // inside of /* */
+	hlStatePreprocessor // preprocessor line continued with \
+)
+
+var ckeywords = makeset(`auto break case char const continue default do double else enum extern float for goto
+	if inline int long register restrict return short signed sizeof static struct switch typedef union unsigned
+	void volatile while _Bool _Complex _Alignas _Alignof _Atomic _Noreturn _Static_assert _Thread_local
+	bool true false`)
+
+var cppkeywords = makeset(`alignas alignof and and_eq asm catch class compl concept consteval constexpr constinit
+	const_cast co_await co_return co_yield decltype delete dynamic_cast explicit export friend mutable namespace
+	new noexcept not not_eq nullptr operator or or_eq override final private protected public reinterpret_cast
+	requires static_assert static_cast template this thread_local throw try typeid typename using virtual
+	wchar_t xor xor_eq`)
+
+var fortrankeywords = makeset(`allocatable allocate assign associate asynchronous backspace bind block call case
+	character class close codimension common complex contains contiguous continue cycle data deallocate default
+	dimension do double elemental else elseif elsewhere end enddo endif endwhere entry enum enumerator equivalence
+	exit extends external final flush forall format function generic go goto if implicit import impure in include
+	inout integer intent interface intrinsic kind len logical module namelist none non_overridable nopass nullify
+	only open operator optional out parameter pass pause pointer precision print private procedure program
+	protected public pure read real recursive result return rewind save select sequence stop submodule subroutine
+	target then to type use value volatile wait where while write`)
+
+// makeset returns a set of the white space separated words
+func makeset(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
+
+// sourcelanguage guesses the language of a source file from its extension
+func sourcelanguage(filename string) int {
+	switch filepath.Ext(filename) {
+	case ".c", ".h", ".i":
+		return langC
+	case ".cpp", ".cc", ".cxx", ".c++", ".C", ".hpp", ".hh", ".hxx", ".H", ".ii", ".tcc":
+		return langCPP
+	case ".f90", ".F90", ".f95", ".F95", ".f03", ".F03", ".f08", ".F08":
+		return langFortranFree
+	case ".f", ".F", ".for", ".FOR", ".f77", ".F77", ".ftn", ".FTN":
+		return langFortranFixed
+	}
+	return langNone
+}
+
+// highlightline returns the colors of each character of a line and the state at its end
+func highlightline(lang int, line string, state int) ([]int16, int) {
+	colors := make([]int16, len(line))
+	for i := range colors {
+		colors[i] = hlNormal
+	}
+	if lang != langNone && state == hlStateNormal && isdirectiveline(line) {
+		fill(colors, 0, len(line), hlDirective)
+		return colors, hlStateNormal
+	}
+	switch lang {
+	case langC, langCPP:
+		return colors, highlightc(lang, line, colors, state)
+	case langFortranFree:
+		highlightfortran(line, colors, 0)
+	case langFortranFixed:
+		highlightfixed(line, colors)
+	}
+	return colors, hlStateNormal
+}
+
+// fill colors a range of characters
+func fill(colors []int16, from, to int, color int16) {
+	for i := from; i < to && i < len(colors); i++ {
+		colors[i] = color
+	}
+}
+
+func isidentstart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isidentchar(c byte) bool {
+	return isidentstart(c) || isdigit(c)
+}
+
+// scannumber returns the end of a number starting at i, including exponents and suffixes
+func scannumber(line string, i int, fortran bool) int {
+	for i < len(line) {
+		c := line[i]
+		switch {
+		case isidentchar(c) || c == '.':
+			// exponent with sign, like 1e-5, 0x1p+3 or 1.0d+0
+			if i+1 < len(line) && (line[i+1] == '+' || line[i+1] == '-') &&
+				(c == 'e' || c == 'E' || c == 'p' || c == 'P' || fortran && (c == 'd' || c == 'D')) {
+				i++
+			}
+			// Fortran operators after numbers, like 1.eq.
+			if c == '.' && fortran && fortranoperator(line[i:]) > 0 {
+				return i
+			}
+			i++
+		case c == '\'' && !fortran && i+1 < len(line) && isidentchar(line[i+1]):
+			i++ // digit separator
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// scanstring returns the end of a string starting with the quote at i, backslash escapes for C
+func scanstring(line string, i int, escapes bool) int {
+	quote := line[i]
+	for i++; i < len(line); i++ {
+		if escapes && line[i] == '\\' {
+			i++
+			continue
+		}
+		if line[i] == quote {
+			return i + 1
+		}
+	}
+	return len(line)
+}
+
+// highlightc colors a line of C or C++, returns the state at its end
+func highlightc(lang int, line string, colors []int16, state int) int {
+	i := 0
+	if state == hlStatePreprocessor {
+		fill(colors, 0, len(line), hlPreprocessor)
+		if strings.HasSuffix(strings.TrimRight(line, " "), "\\") {
+			return hlStatePreprocessor
+		}
+		return hlStateNormal
+	}
+	if state == hlStateComment {
+		end := strings.Index(line, "*/")
+		if end == -1 {
+			fill(colors, 0, len(line), hlComment)
+			return hlStateComment
+		}
+		fill(colors, 0, end+2, hlComment)
+		i = end + 2
+	}
+	if trimmed := strings.TrimLeft(line, " "); i == 0 && strings.HasPrefix(trimmed, "#") {
+		fill(colors, 0, len(line), hlPreprocessor)
+		// comments behind preprocessor lines
+		if c := strings.Index(line, "//"); c >= 0 {
+			fill(colors, c, len(line), hlComment)
+		}
+		if strings.HasSuffix(strings.TrimRight(line, " "), "\\") {
+			return hlStatePreprocessor
+		}
+		return hlStateNormal
+	}
+	for i < len(line) {
+		c := line[i]
+		switch {
+		case strings.HasPrefix(line[i:], "//"):
+			fill(colors, i, len(line), hlComment)
+			return hlStateNormal
+		case strings.HasPrefix(line[i:], "/*"):
+			end := strings.Index(line[i+2:], "*/")
+			if end == -1 {
+				fill(colors, i, len(line), hlComment)
+				return hlStateComment
+			}
+			fill(colors, i, i+end+4, hlComment)
+			i += end + 4
+		case c == '"' || c == '\'':
+			end := scanstring(line, i, true)
+			fill(colors, i, end, hlString)
+			i = end
+		case isdigit(c) || c == '.' && i+1 < len(line) && isdigit(line[i+1]):
+			end := scannumber(line, i, false)
+			fill(colors, i, end, hlNumber)
+			i = end
+		case isidentstart(c):
+			end := i
+			for end < len(line) && isidentchar(line[end]) {
+				end++
+			}
+			word := line[i:end]
+			if ckeywords[word] || lang == langCPP && cppkeywords[word] {
+				fill(colors, i, end, hlKeyword)
+			}
+			i = end
+		default:
+			i++
+		}
+	}
+	return hlStateNormal
+}
+
+// highlightfortran colors free form Fortran starting at column from
+func highlightfortran(line string, colors []int16, from int) {
+	if from == 0 && strings.HasPrefix(strings.TrimLeft(line, " "), "#") {
+		fill(colors, 0, len(line), hlPreprocessor)
+		return
+	}
+	for i := from; i < len(line); {
+		c := line[i]
+		switch {
+		case c == '!':
+			// directives like !$omp, !dir$ or !NEC$ are shown like preprocessor lines
+			color := hlComment
+			if isdirective(line[i:]) {
+				color = hlPreprocessor
+			}
+			fill(colors, i, len(line), color)
+			return
+		case c == '"' || c == '\'':
+			end := scanstring(line, i, false)
+			// doubled quotes continue the string
+			for end < len(line) && line[end] == c {
+				end = scanstring(line, end, false)
+			}
+			fill(colors, i, end, hlString)
+			i = end
+		case isdigit(c) || c == '.' && i+1 < len(line) && isdigit(line[i+1]):
+			end := scannumber(line, i, true)
+			fill(colors, i, end, hlNumber)
+			i = end
+		case c == '.':
+			if n := fortranoperator(line[i:]); n > 0 {
+				fill(colors, i, i+n, hlKeyword)
+				i += n
+			} else {
+				i++
+			}
+		case isidentstart(c):
+			end := i
+			for end < len(line) && isidentchar(line[end]) {
+				end++
+			}
+			if fortrankeywords[strings.ToLower(line[i:end])] {
+				fill(colors, i, end, hlKeyword)
+			}
+			i = end
+		default:
+			i++
+		}
+	}
+}
+
+// fortranoperator returns the length of an operator or logical constant like .and. or .true., 0 if none
+func fortranoperator(s string) int {
+	end := 1
+	for end < len(s) && (s[end] >= 'a' && s[end] <= 'z' || s[end] >= 'A' && s[end] <= 'Z') {
+		end++
+	}
+	if end > 2 && end < len(s) && s[end] == '.' {
+		return end + 1
+	}
+	return 0
+}
+
+// highlightfixed colors fixed form Fortran: comment lines, labels, continuation and columns after 72
+func highlightfixed(line string, colors []int16) {
+	if len(line) == 0 {
+		return
+	}
+	switch line[0] {
+	case 'c', 'C', '*', '!':
+		color := hlComment
+		if isdirective(line) {
+			color = hlPreprocessor
+		}
+		fill(colors, 0, len(line), color)
+		return
+	case '#':
+		fill(colors, 0, len(line), hlPreprocessor)
+		return
+	}
+	fill(colors, 0, 5, hlNumber)
+	if len(line) > 5 && line[5] != ' ' && line[5] != '0' {
+		colors[5] = hlKeyword
+	}
+	code := line
+	if len(code) > 72 {
+		code = code[:72]
+		fill(colors, 72, len(line), hlComment)
+	}
+	if len(code) > 6 {
+		highlightfortran(code, colors, 6)
+	}
+}
+
+// isdirective tells if a Fortran comment is a compiler directive
+func isdirective(comment string) bool {
+	c := strings.ToLower(comment)
+	if len(c) < 2 {
+		return false
+	}
+	c = c[1:]
+	return strings.HasPrefix(c, "$") || strings.HasPrefix(c, "dir$") || strings.HasPrefix(c, "dec$") ||
+		strings.HasPrefix(c, "nec$") || strings.HasPrefix(c, "cdir") || strings.HasPrefix(c, "ocl")
+}
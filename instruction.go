@@ -0,0 +1,156 @@
+package main
+
+/*
+	lightweight parsing of single assembler lines
+
+	we do not want to write a complete assembler parser,
+	for the analysis it is good enough to know the mnemonic
+	and the operands of instruction lines.
+	works for VE syntax as well as for x86-64 AT&T syntax.
+*/
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Instruction is a parsed instruction line
+type Instruction struct {
+	mnemonic string   // mnemonic including suffixes like .l or .d
+	operands []string // operands as written, split at top level commas
+}
+
+// MemoryOperand is a memory reference like -8(,%fp) or 16(%rsp,%rax,8)
+type MemoryOperand struct {
+	disp  string // displacement as written, can be a symbol
+	base  string // base register
+	index string // index register
+}
+
+var reregister = regexp.MustCompile(`%[a-z][a-z0-9]*`)
+var relabeldef = regexp.MustCompile(`^([^\s#:]+):`)
+
+// x86 prefixes which are written in front of the mnemonic
+var x86prefixes = map[string]bool{
+	"rep": true, "repe": true, "repz": true, "repne": true, "repnz": true,
+	"lock": true, "notrack": true, "data16": true, "bnd": true,
+}
+
+// parseInstruction returns the instruction in line, ok is false for labels, directives, comments and empty lines
+func parseInstruction(line string) (Instruction, bool) {
+	var ins Instruction
+
+	if len(line) == 0 || (line[0] != ' ' && line[0] != '\t') {
+		return ins, false
+	}
+	line = strings.TrimSpace(line)
+	if len(line) == 0 || line[0] < 'a' || line[0] > 'z' {
+		return ins, false
+	}
+	// cut trailing comments
+	if pos := strings.IndexByte(line, '#'); pos != -1 {
+		line = strings.TrimSpace(line[:pos])
+	}
+
+	pos := strings.IndexAny(line, " \t")
+	if pos == -1 {
+		ins.mnemonic = line
+		return ins, true
+	}
+	ins.mnemonic = line[:pos]
+	rest := strings.TrimSpace(line[pos:])
+	if x86prefixes[ins.mnemonic] && len(rest) > 0 && rest[0] >= 'a' && rest[0] <= 'z' {
+		return parseInstruction(" " + rest)
+	}
+	ins.operands = splitoperands(rest)
+	return ins, true
+}
+
+// splitoperands splits at commas which are not inside of () or {}
+func splitoperands(ops string) []string {
+	result := make([]string, 0, 4)
+	if ops == "" {
+		return result
+	}
+	level := 0
+	start := 0
+	for pos := 0; pos < len(ops); pos++ {
+		switch ops[pos] {
+		case '(', '{':
+			level++
+		case ')', '}':
+			level--
+		case ',':
+			if level == 0 {
+				result = append(result, strings.TrimSpace(ops[start:pos]))
+				start = pos + 1
+			}
+		}
+	}
+	return append(result, strings.TrimSpace(ops[start:]))
+}
+
+// basemnemonic returns the mnemonic without suffixes, vfmad.d -> vfmad
+func (i Instruction) basemnemonic() string {
+	if pos := strings.IndexByte(i.mnemonic, '.'); pos > 0 {
+		return i.mnemonic[:pos]
+	}
+	return i.mnemonic
+}
+
+// registersin returns all registers used in operand
+func registersin(operand string) []string {
+	return reregister.FindAllString(operand, -1)
+}
+
+// parsememory parses a memory operand, VE syntax is disp(index,base), x86 syntax is disp(base,index,scale)
+func parsememory(operand string, ve bool) (MemoryOperand, bool) {
+	var m MemoryOperand
+
+	open := strings.IndexByte(operand, '(')
+	if open == -1 || operand[len(operand)-1] != ')' {
+		return m, false
+	}
+	m.disp = strings.TrimSpace(operand[:open])
+	if strings.HasPrefix(m.disp, "*") {
+		m.disp = m.disp[1:]
+	}
+	parts := strings.Split(operand[open+1:len(operand)-1], ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	if ve {
+		if len(parts) == 1 {
+			m.base = parts[0]
+		} else {
+			m.index = parts[0]
+			m.base = parts[1]
+		}
+	} else {
+		m.base = parts[0]
+		if len(parts) > 1 {
+			m.index = parts[1]
+		}
+	}
+	return m, true
+}
+
+// labelof returns the label defined in line or ""
+func labelof(line string) string {
+	m := relabeldef.FindStringSubmatch(line)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// parseint parses decimal and hex numbers as used in both syntaxes
+func parseint(s string) (int64, bool) {
+	s = strings.TrimPrefix(s, "$")
+	v, err := strconv.ParseInt(s, 0, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
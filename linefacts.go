@@ -0,0 +1,73 @@
+package main
+
+/*
+	code generation facts of source lines
+
+	for each source line, the instructions attributed to it by .loc
+	are counted, together with the number of separate blocks of them
+	(a block ends when an instruction of another source line follows),
+	so copies from unrolling or loop versioning show up as several
+	blocks. a line is flagged vector if any of its instructions is
+	vector code. the source panel shows these facts in its gutter.
+*/
+
+import (
+	"fmt"
+)
+
+// LineFacts are the facts of the code generated for one source line
+type LineFacts struct {
+	instructions int  // number of instructions
+	blocks       int  // number of separate runs of instructions
+	vector       bool // any vector or packed SIMD instruction
+}
+
+// linefacts returns the facts for the lines of a source file, indexed by source line number
+func (a *AssemblerFile) linefacts(filename string) map[int]*LineFacts {
+	facts := make(map[int]*LineFacts)
+	ids := make(map[int]bool)
+	for fileid, name := range a.filenametable {
+		if samesource(name, filename) {
+			ids[fileid] = true
+		}
+	}
+	if len(ids) == 0 {
+		return facts
+	}
+	last := loctuple{}
+	for l := 1; l < len(a.index); l++ {
+		ins, ok := parseInstruction(a.filebuffer.GetLine(l))
+		if !ok {
+			continue
+		}
+		loc := a.index[l].loc
+		if ids[loc.fileid] && loc.linenr > 0 {
+			f, ok := facts[loc.linenr]
+			if !ok {
+				f = &LineFacts{}
+				facts[loc.linenr] = f
+			}
+			f.instructions++
+			if loc.linenr != last.linenr || !ids[last.fileid] {
+				f.blocks++
+			}
+			if isvectorclass(a.classify(ins)) {
+				f.vector = true
+			}
+		}
+		last = loc
+	}
+	return facts
+}
+
+// gutter returns the text in front of a source line, line number and facts if there are any
+func (f *LineFacts) gutter(linenr int) string {
+	if f == nil {
+		return fmt.Sprintf("%7d %4s %2s  : ", linenr, "", "")
+	}
+	flag := "S"
+	if f.vector {
+		flag = "V"
+	}
+	return fmt.Sprintf("%7d %4d %2d %s: ", linenr, f.instructions, f.blocks, flag)
+}
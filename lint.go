@@ -0,0 +1,232 @@
+package main
+
+/*
+	lint rules for generated code
+
+	each rule checks the whole file and returns findings, a line
+	with a message. built in rules look for typical performance
+	problems, more rules can be added to lintrules, or read from
+	a rules file given with --rules, one rule per line:
+
+		<name> <scope> <regexp> <message>
+
+	scope is any, loop or inner (innermost loops), regexp is matched
+	against instruction lines and must not contain blanks (use \s),
+	the rest of the line is the message, like
+
+		sqrt-in-loop inner sqrt square root in inner loop
+*/
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Finding is a problem found by a lint rule
+type Finding struct {
+	line    int
+	rule    string
+	message string
+}
+
+// LintRule checks the whole file and returns its findings
+type LintRule struct {
+	name  string
+	check func(a *AssemblerFile) []Finding
+}
+
+// lintrules are the rules run on each file, the built in ones are
+// div-in-loop      division inside of a loop
+// vzeroupper       call after 256 bit code without vzeroupper (x86)
+// scalar-in-loop   scalar floating point code in an inner loop
+// gather-in-loop   gather or scatter in an inner loop (VE)
+// repeated-reload  stack slot loaded again in a loop without store in between
+var lintrules = []LintRule{
+	{"div-in-loop", lintdivision},
+	{"vzeroupper", lintvzeroupper},
+	{"scalar-in-loop", lintscalar},
+	{"gather-in-loop", lintgather},
+	{"repeated-reload", lintreload},
+}
+
+// lint runs all rules and stores the findings sorted by line
+func (a *AssemblerFile) lint(rules []LintRule) {
+	a.findings = make([]Finding, 0, 64)
+	for _, r := range rules {
+		a.findings = append(a.findings, r.check(a)...)
+	}
+	sort.SliceStable(a.findings, func(i, j int) bool { return a.findings[i].line < a.findings[j].line })
+	a.findingsat = make(map[int][]int)
+	for i, f := range a.findings {
+		a.findingsat[f.line] = append(a.findingsat[f.line], i)
+	}
+}
+
+// lintreport returns one list entry per finding
+func (a *AssemblerFile) lintreport() []ListEntry {
+	entries := make([]ListEntry, 0, len(a.findings))
+	for _, f := range a.findings {
+		entries = append(entries, ListEntry{fmt.Sprintf("%8d  %-16s %s", f.line, f.rule, f.message), f.line})
+	}
+	return entries
+}
+
+// inloop checks if a line is in a loop, inner restricts to innermost loops
+func (a *AssemblerFile) inloop(line int, inner bool) bool {
+	li := a.loopof(line)
+	return li != -1 && (!inner || a.loops[li].inner)
+}
+
+// lintdivision finds divisions inside of loops
+func lintdivision(a *AssemblerFile) []Finding {
+	findings := make([]Finding, 0)
+	for l := 1; l < len(a.index); l++ {
+		if ins, ok := parseInstruction(a.filebuffer.GetLine(l)); ok && a.inloop(l, false) &&
+			strings.Contains(ins.basemnemonic(), "div") {
+			findings = append(findings, Finding{l, "div-in-loop", ins.mnemonic + " inside of loop " + a.loopname(a.loopof(l)) + ", consider multiplying with the reciprocal"})
+		}
+	}
+	return findings
+}
+
+// lintvzeroupper finds calls after use of 256 or 512 bit registers without vzeroupper in between
+func lintvzeroupper(a *AssemblerFile) []Finding {
+	findings := make([]Finding, 0)
+	if a.ve {
+		return findings
+	}
+	for _, f := range a.functions {
+		dirty := 0 // line of last wide instruction
+		for l := f.start; l <= f.end; l++ {
+			ins, ok := parseInstruction(a.filebuffer.GetLine(l))
+			if !ok {
+				continue
+			}
+			switch {
+			case ins.mnemonic == "vzeroupper" || ins.mnemonic == "vzeroall":
+				dirty = 0
+			case strings.HasPrefix(ins.mnemonic, "call"):
+				if dirty != 0 {
+					findings = append(findings, Finding{l, "vzeroupper", fmt.Sprintf("call without vzeroupper after 256 bit code in line %d, causes AVX-SSE transition penalty", dirty)})
+				}
+			default:
+				if w := simdwidth(ins); w == width256 || w == width512 {
+					dirty = l
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// lintscalar finds inner loops with scalar floating point arithmetic, reported once per loop
+func lintscalar(a *AssemblerFile) []Finding {
+	findings := make([]Finding, 0)
+	for li, loop := range a.loops {
+		if !loop.inner {
+			continue
+		}
+		first, count := 0, 0
+		for l := loop.head; l <= loop.latch; l++ {
+			ins, ok := parseInstruction(a.filebuffer.GetLine(l))
+			if !ok || a.classify(ins) != classScalarFP {
+				continue
+			}
+			if first == 0 {
+				first = l
+			}
+			count++
+		}
+		if count > 0 {
+			findings = append(findings, Finding{first, "scalar-in-loop", fmt.Sprintf("%d scalar floating point instructions in inner loop %s, loop is not vectorized", count, a.loopname(li))})
+		}
+	}
+	return findings
+}
+
+// lintgather finds gathers and scatters in innermost VE loops
+func lintgather(a *AssemblerFile) []Finding {
+	findings := make([]Finding, 0)
+	if !a.ve {
+		return findings
+	}
+	for l := 1; l < len(a.index); l++ {
+		if ins, ok := parseInstruction(a.filebuffer.GetLine(l)); ok && a.inloop(l, true) {
+			if class := classifyVE(ins); class == classGather || class == classScatter {
+				findings = append(findings, Finding{l, "gather-in-loop", classnames[class] + " in inner loop " + a.loopname(a.loopof(l)) + ", check the access pattern"})
+			}
+		}
+	}
+	return findings
+}
+
+// lintreload finds stack slots loaded again inside of a loop without a store in between
+func lintreload(a *AssemblerFile) []Finding {
+	findings := make([]Finding, 0)
+	for li, loop := range a.loops {
+		lastload := make(map[int]int) // slot -> line of last load
+		for l := loop.head; l <= loop.latch; l++ {
+			access, ok := a.stackaccess[l]
+			if !ok || a.loopof(l) != li {
+				continue
+			}
+			if access.store {
+				delete(lastload, access.slot)
+			}
+			if access.load {
+				if prev, ok := lastload[access.slot]; ok {
+					findings = append(findings, Finding{l, "repeated-reload", fmt.Sprintf("stack slot %s reloaded, already loaded in line %d", a.stackslots[access.slot].name, prev)})
+				}
+				lastload[access.slot] = l
+			}
+		}
+	}
+	return findings
+}
+
+// readlintrules reads rules from a file and appends them to lintrules
+func readlintrules(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	linenr := 0
+	for scanner.Scan() {
+		linenr++
+		flds := strings.Fields(scanner.Text())
+		if len(flds) == 0 || flds[0][0] == '#' {
+			continue
+		}
+		if len(flds) < 4 {
+			return fmt.Errorf("%s line %d: expected '<name> <scope> <regexp> <message>'", filename, linenr)
+		}
+		name, scope, message := flds[0], flds[1], strings.Join(flds[3:], " ")
+		if scope != "any" && scope != "loop" && scope != "inner" {
+			return fmt.Errorf("%s line %d: scope must be any, loop or inner", filename, linenr)
+		}
+		re, err := regexp.Compile(flds[2])
+		if err != nil {
+			return fmt.Errorf("%s line %d: %s", filename, linenr, err)
+		}
+		lintrules = append(lintrules, LintRule{name, func(a *AssemblerFile) []Finding {
+			findings := make([]Finding, 0)
+			for l := 1; l < len(a.index); l++ {
+				line := a.filebuffer.GetLine(l)
+				if _, ok := parseInstruction(line); !ok || !re.MatchString(line) {
+					continue
+				}
+				if scope == "any" || a.inloop(l, scope == "inner") {
+					findings = append(findings, Finding{l, name, message})
+				}
+			}
+			return findings
+		}})
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,105 @@
+package main
+
+/*
+	list popup
+
+	a modal list drawn over the top panel, used for symbol lists
+	and analysis results. each entry can refer to a line in the
+	assembler file, selecting the entry with <enter> jumps there.
+*/
+
+import (
+	"fmt"
+
+	gc "github.com/rthornton128/goncurses"
+)
+
+// ListEntry is one line in a list popup, line is the assembler line it refers to (0 = none)
+type ListEntry struct {
+	text string
+	line int
+}
+
+// showlist displays a modal list over the top panel and returns index of selected entry or -1
+func (t *TuiT) showlist(title string, entries []ListEntry) int {
+	return t.showlistat(title, entries, 0)
+}
+
+// showlistat is showlist with the cursor on entry cursor at start
+func (t *TuiT) showlistat(title string, entries []ListEntry, cursor int) int {
+	win, err := gc.NewWindow(t.toplines, t.maxx, 0, 0)
+	if err != nil {
+		return -1
+	}
+	win.Keypad(true)
+	defer func() {
+		win.Erase()
+		win.NoutRefresh()
+		win.Delete()
+		t.top.Touch()
+		t.Refreshtopall()
+		t.drawother()
+	}()
+
+	rows := t.toplines - 1          // first line is title
+	first := maxi(0, cursor-rows+1) // first entry on screen
+
+	for {
+		// draw title and visible entries
+		win.Erase()
+		win.AttrOn(gc.A_REVERSE)
+		win.MovePrint(0, 0, fmt.Sprintf("%-*s", t.maxx, fmt.Sprintf(" %s  (%d/%d)", title, mini(cursor+1, len(entries)), len(entries))))
+		win.AttrOff(gc.A_REVERSE)
+		for y := 0; y < rows && first+y < len(entries); y++ {
+			text := entries[first+y].text
+			if len(text) > t.maxx {
+				text = text[:t.maxx]
+			}
+			if first+y == cursor {
+				win.ColorOn(2)
+			} else {
+				win.ColorOn(1)
+			}
+			win.MovePrint(y+1, 0, fmt.Sprintf("%-*s", t.maxx, text))
+		}
+		win.ColorOn(1)
+		win.NoutRefresh()
+		gc.Update()
+
+		switch win.GetChar() {
+		case gc.KEY_DOWN, 'j':
+			cursor = mini(cursor+1, len(entries)-1)
+		case gc.KEY_UP, 'k':
+			cursor = maxi(cursor-1, 0)
+		case gc.KEY_PAGEDOWN:
+			cursor = mini(cursor+rows, len(entries)-1)
+		case gc.KEY_PAGEUP:
+			cursor = maxi(cursor-rows, 0)
+		case gc.KEY_HOME:
+			cursor = 0
+		case gc.KEY_END, 'G':
+			cursor = len(entries) - 1
+		case gc.KEY_RETURN:
+			if len(entries) > 0 {
+				return cursor
+			}
+			return -1
+		case 'q', gc.KEY_ESC:
+			return -1
+		}
+		cursor = maxi(cursor, 0)
+		if cursor < first {
+			first = cursor
+		} else if cursor >= first+rows {
+			first = cursor - rows + 1
+		}
+	}
+}
+
+// jumplist shows a list and jumps to the line of the selected entry
+func (t *TuiT) jumplist(title string, entries []ListEntry) {
+	selected := t.showlist(title, entries)
+	if selected != -1 && entries[selected].line > 0 {
+		t.showlinetop(entries[selected].line)
+	}
+}
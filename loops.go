@@ -0,0 +1,142 @@
+package main
+
+/*
+	loop detection
+
+	a loop is found by a backward branch to a label inside
+	the same function, the label is the head of the loop,
+	the branch is the latch.
+	several backward branches to the same label form one loop.
+	this is not a real control flow analysis, but compilers
+	lay out loops this way, so it is good enough to find them.
+*/
+
+import (
+	"sort"
+)
+
+// Loop is a loop detected by a backward branch to a label
+type Loop struct {
+	head, latch int  // first line (label) and line of last backward branch
+	function    int  // index into functions
+	depth       int  // nesting depth, 1 = outermost
+	inner       bool // contains no other loop
+}
+
+// collectlabels builds the table of labels and their lines
+func (a *AssemblerFile) collectlabels() {
+	a.labels = make(map[string]int)
+	for l := 1; l < len(a.index); l++ {
+		line := a.filebuffer.GetLine(l)
+		if len(line) > 0 && line[0] != ' ' && line[0] != '#' {
+			if label := labelof(line); label != "" {
+				a.labels[label] = l
+			}
+		}
+	}
+}
+
+// isbranch checks if instruction is a jump or branch, calls are not branches
+func isbranch(ins Instruction, ve bool) bool {
+	if ve {
+		base := ins.basemnemonic()
+		return base[0] == 'b' && base != "bsic" && base != "bswp" && base != "brv"
+	}
+	return ins.mnemonic[0] == 'j'
+}
+
+// branchtarget returns the label a branch jumps to, or "" for indirect branches
+func (a *AssemblerFile) branchtarget(ins Instruction) string {
+	if !isbranch(ins, a.ve) || len(ins.operands) == 0 {
+		return ""
+	}
+	target := ins.operands[len(ins.operands)-1]
+	if _, ok := a.labels[target]; ok {
+		return target
+	}
+	return ""
+}
+
+// findloops searches backward branches in all functions and computes the nesting of loops
+func (a *AssemblerFile) findloops() {
+	a.loops = make([]Loop, 0, 64)
+	for fi := range a.functions {
+		f := &a.functions[fi]
+		heads := make(map[int]int) // head line -> index into loops
+		for l := f.start; l <= f.end; l++ {
+			ins, ok := parseInstruction(a.filebuffer.GetLine(l))
+			if !ok {
+				continue
+			}
+			target := a.branchtarget(ins)
+			if target == "" {
+				continue
+			}
+			head := a.labels[target]
+			if head >= l || head < f.start {
+				continue
+			}
+			if li, ok := heads[head]; ok {
+				a.loops[li].latch = l
+			} else {
+				heads[head] = len(a.loops)
+				a.loops = append(a.loops, Loop{head: head, latch: l, function: fi})
+			}
+		}
+	}
+
+	// loops are found ordered by latch inside a function, we want them by head
+	sort.SliceStable(a.loops, func(i, j int) bool {
+		if a.loops[i].function != a.loops[j].function {
+			return a.loops[i].function < a.loops[j].function
+		}
+		return a.loops[i].head < a.loops[j].head
+	})
+
+	// nesting, a loop is inside all loops which contain its range
+	for i := range a.loops {
+		a.loops[i].depth = 1
+		a.loops[i].inner = true
+		a.functions[a.loops[i].function].loops = append(a.functions[a.loops[i].function].loops, i)
+	}
+	for _, f := range a.functions {
+		for _, i := range f.loops {
+			for _, j := range f.loops {
+				if i != j && a.loops[i].head <= a.loops[j].head && a.loops[j].latch <= a.loops[i].latch {
+					a.loops[j].depth++
+					a.loops[i].inner = false
+				}
+			}
+		}
+	}
+
+	// store innermost loop for each line, outer loops are handled first
+	for _, f := range a.functions {
+		for _, i := range f.loops {
+			for l := a.loops[i].head; l <= a.loops[i].latch; l++ {
+				cur := a.index[l].loop
+				if cur == 0 || a.loops[cur-1].depth < a.loops[i].depth {
+					a.index[l].loop = i + 1
+				}
+			}
+		}
+	}
+}
+
+// loopof returns the index of innermost loop containing line, or -1
+func (a *AssemblerFile) loopof(line int) int {
+	if line < 1 || line >= len(a.index) {
+		return -1
+	}
+	return a.index[line].loop - 1
+}
+
+// loopname returns a short description of a loop for lists and status messages
+func (a *AssemblerFile) loopname(li int) string {
+	loop := a.loops[li]
+	name := labelof(a.filebuffer.GetLine(loop.head))
+	if loop.inner {
+		return name + " (inner)"
+	}
+	return name
+}
@@ -15,6 +15,7 @@ import (
 	"os"
 	"runtime"
 	"runtime/pprof"
+	"strings"
 
 	flags "github.com/jessevdk/go-flags"
 )
@@ -23,7 +24,13 @@ var version string
 
 var opts struct {
 	Profile    bool   `long:"profile" short:"p" description:"profile the application"`
+	Raw        bool   `long:"raw" description:"show raw instead of demangled symbol names"`
 	Sourcedirs string `long:"sourcedirs" short:"s" description:"comma seperated list of directories to search for source files"`
+	Timings    string `long:"timings" short:"t" description:"file with additional instruction latencies and throughputs"`
+	Format     string `long:"format" short:"f" default:"table" description:"output format of stats: table, json or csv"`
+	HTML       string `long:"html" description:"output directory of export"`
+	Rules      string `long:"rules" short:"r" description:"file with additional lint rules"`
+	Uarch      string `long:"uarch" short:"u" default:"skylake" description:"x86 microarchitecture for timing estimates (skylake, zen2)"`
 }
 
 var assemblerfile *AssemblerFile
@@ -51,10 +58,42 @@ func main() {
 
 	if len(args) < 1 {
 		fmt.Println("veass version", version)
-		fmt.Println("usage: veass [-s|-sourcedirs dir1[,dir2,...] <file.s>")
+		fmt.Println("usage: veass [-s|-sourcedirs dir1[,dir2,...] <file.s> [other.s]")
+		fmt.Println("       veass check <spec.yaml> <file.s>")
+		fmt.Println("       veass stats [-f|--format table|json|csv] <file.s> [file.s ...]")
+		fmt.Println("       veass diff <old.s> <new.s> [symbol ...]")
+		fmt.Println("       veass export --html <outdir> <file.s>")
 		os.Exit(0)
 	}
 
+	rawnames = opts.Raw
+
+	// before the subcommands, export marks findings as well
+	if opts.Rules != "" {
+		if err := readlintrules(opts.Rules); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	if args[0] == "check" {
+		os.Exit(runcheck(args[1:]))
+	}
+	if args[0] == "stats" {
+		os.Exit(runstats(args[1:], opts.Format))
+	}
+	if args[0] == "diff" {
+		os.Exit(rundiff(args[1:]))
+	}
+	if args[0] == "export" {
+		os.Exit(runexport(args[1:], opts.HTML))
+	}
+
+	if err := inittimings(opts.Timings); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
 	filename := args[0]
 	if filename[len(filename)-2:] == ".s" {
 		assemblerfile, err = NewAssemblerFile(filename)
@@ -66,11 +105,31 @@ func main() {
 		os.Exit(1)
 	}
 
+	assemblerfile.lint(lintrules)
+
+	// second file for side by side comparison
+	var comparefile *AssemblerFile
+	if len(args) > 1 {
+		if !strings.HasSuffix(args[1], ".s") {
+			fmt.Println("unknown file type of", args[1])
+			os.Exit(1)
+		}
+		comparefile, err = NewAssemblerFile(args[1])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		comparefile.lint(lintrules)
+	}
+
 	assemblermodel := NewAssemblerModel(assemblerfile)
 
 	tui := NewTui()
 
 	tui.topmodel = assemblermodel
+	if comparefile != nil {
+		tui.opencompare(comparefile)
+	}
 
 	tui.Run()
 
@@ -0,0 +1,99 @@
+package main
+
+/*
+	x86 SIMD width per loop and per function
+
+	instructions are classified as scalar (ss/sd), 128, 256 or
+	512 bit packed by mnemonic and register class. for a loop,
+	only the lines which are not inside of a nested loop are
+	counted, so an outer loop around a vectorized inner loop
+	does not look mixed.
+	this is the x86 counterpart of the VE vectorization report.
+*/
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WidthMix counts x86 instructions by SIMD width
+type WidthMix struct {
+	widths [nrwidths]int
+	masked int // instructions using AVX-512 masking
+}
+
+// widthmix counts the widths in a range of lines, if loop is not -1 only lines with innermost loop loop are counted
+func (a *AssemblerFile) widthmix(from, to int, loop int) WidthMix {
+	var mix WidthMix
+	for l := from; l <= to; l++ {
+		if loop != -1 && a.loopof(l) != loop {
+			continue
+		}
+		ins, ok := parseInstruction(a.filebuffer.GetLine(l))
+		if !ok {
+			continue
+		}
+		mix.widths[simdwidth(ins)]++
+		if masked(ins) {
+			mix.masked++
+		}
+	}
+	return mix
+}
+
+// dominant returns the width with most instructions, widthNone if there are no floating point instructions
+func (m WidthMix) dominant() int {
+	dominant := widthNone
+	for w := widthScalar; w < nrwidths; w++ {
+		if m.widths[w] > 0 && (dominant == widthNone || m.widths[w] >= m.widths[dominant]) {
+			dominant = w
+		}
+	}
+	return dominant
+}
+
+// mixed returns the names of the widths if more than one is used, or ""
+func (m WidthMix) mixed() string {
+	used := make([]string, 0, 4)
+	for w := widthScalar; w < nrwidths; w++ {
+		if m.widths[w] > 0 {
+			used = append(used, widthnames[w])
+		}
+	}
+	if len(used) > 1 {
+		return strings.Join(used, "/")
+	}
+	return ""
+}
+
+// format returns a line for the report, warn adds a warning for mixed widths
+func (m WidthMix) format(name string, warn bool) string {
+	maskinfo := "-"
+	if m.masked > 0 {
+		maskinfo = fmt.Sprintf("%d", m.masked)
+	}
+	warning := ""
+	if mixed := m.mixed(); warn && mixed != "" {
+		warning = "  ! mixes " + mixed
+	}
+	return fmt.Sprintf("%-6s %6d %5d %5d %5d %6s  %s%s", widthnames[m.dominant()],
+		m.widths[widthScalar], m.widths[width128], m.widths[width256], m.widths[width512], maskinfo, name, warning)
+}
+
+// simdreport returns list entries for all functions with loops and their loops
+func (a *AssemblerFile) simdreport() []ListEntry {
+	entries := make([]ListEntry, 0, len(a.loops)+len(a.functions))
+	for _, f := range a.functions {
+		mix := a.widthmix(f.start, f.end, -1)
+		if len(f.loops) == 0 && mix.dominant() == widthNone {
+			continue
+		}
+		entries = append(entries, ListEntry{mix.format(symbolname(f.name), false), f.start})
+		for _, li := range f.loops {
+			loop := a.loops[li]
+			lmix := a.widthmix(loop.head, loop.latch, li)
+			entries = append(entries, ListEntry{lmix.format(strings.Repeat(" ", loop.depth+1)+a.loopname(li), true), loop.head})
+		}
+	}
+	return entries
+}
@@ -0,0 +1,80 @@
+package main
+
+/*
+	tests of the x86 instruction classes and SIMD widths
+*/
+
+import "testing"
+
+func TestSimdWidth(t *testing.T) {
+	tests := []struct {
+		line   string
+		width  int
+		masked bool
+	}{
+		{"\taddq\t$1, %rax", widthNone, false},
+		{"\taddsd\t(%rax), %xmm0", widthScalar, false},
+		{"\tvmulss\t%xmm2, %xmm1, %xmm0", widthScalar, false},
+		{"\tmulpd\t%xmm1, %xmm0", width128, false},
+		{"\tvfmadd231pd\t(%rdi,%rax), %ymm1, %ymm0", width256, false},
+		{"\tvaddpd\t%zmm1, %zmm2, %zmm0{%k1}", width512, true},
+		{"\tvaddpd\t%zmm1, %zmm2, %zmm0{%k0}", width512, false},
+	}
+	for _, test := range tests {
+		ins, _ := parseInstruction(test.line)
+		if width := simdwidth(ins); width != test.width || masked(ins) != test.masked {
+			t.Errorf("width of %q = %s, masked %v, want %s, %v", test.line, widthnames[width], masked(ins), widthnames[test.width], test.masked)
+		}
+	}
+}
+
+func TestClassifyX86(t *testing.T) {
+	tests := []struct {
+		line        string
+		class       int
+		load, store bool
+	}{
+		{"\taddq\t$1, %rax", classScalar, false, false},
+		{"\tmovq\t8(%rax), %rbx", classLoad, true, false},
+		{"\tmovq\t%rbx, 8(%rax)", classStore, false, true},
+		{"\tleaq\t8(%rax), %rbx", classScalar, false, false},
+		{"\taddsd\t(%rax), %xmm0", classScalarFP, true, false},
+		{"\tmovsd\t%xmm0, (%rax)", classStore, false, true},
+		{"\tvmovupd\t(%rsi,%rax), %ymm1", classVectorLoad, true, false},
+		{"\tvmovupd\t%ymm0, (%rdi,%rax)", classVectorStore, false, true},
+		{"\tvaddpd\t%ymm1, %ymm2, %ymm0", classVector, false, false},
+		{"\tvgatherdpd\t%ymm3, (%rax,%xmm2,8), %ymm0", classGather, true, false},
+		{"\tjne\t.L3", classBranch, false, false},
+		{"\tcall\tfoo", classCall, false, false},
+		{"\tkmovw\t%k1, %eax", classMask, false, false},
+		// compares only read a memory operand in last position
+		{"\tcmpq\t$0, 8(%rax)", classScalar, true, false},
+		{"\ttestl\t%edx, (%rax)", classScalar, true, false},
+		{"\tbtq\t$3, (%rax)", classScalar, true, false},
+		{"\tucomisd\t(%rax), %xmm0", classScalarFP, true, false},
+		{"\tvptest\t(%rax), %ymm0", classVectorLoad, true, false},
+		{"\tbtsq\t$3, (%rax)", classScalar, false, true},
+		{"\tcmpxchgq\t%rdx, (%rax)", classScalar, false, true},
+	}
+	for _, test := range tests {
+		ins, _ := parseInstruction(test.line)
+		load, store := memoryaccess(ins)
+		if class := classifyX86(ins); class != test.class || load != test.load || store != test.store {
+			t.Errorf("class of %q = %s, load %v, store %v, want %s, %v, %v",
+				test.line, classnames[class], load, store, classnames[test.class], test.load, test.store)
+		}
+	}
+}
+
+func TestWidthMix(t *testing.T) {
+	var mix WidthMix
+	if mix.dominant() != widthNone || mix.mixed() != "" {
+		t.Errorf("empty mix: dominant %s, mixed %q", widthnames[mix.dominant()], mix.mixed())
+	}
+	mix.widths[widthScalar] = 2
+	mix.widths[width256] = 2
+	mix.widths[width128] = 1
+	if mix.dominant() != width256 || mix.mixed() != "scalar/128/256" {
+		t.Errorf("dominant %s, mixed %q, want 256 and scalar/128/256", widthnames[mix.dominant()], mix.mixed())
+	}
+}
@@ -8,7 +8,6 @@ package main
 
 import (
 	"bufio"
-	"fmt"
 	"os"
 )
 
@@ -38,12 +37,9 @@ func NewSourceFile(filename string) (*Sourcefile, error) {
 		if err != nil {
 			break
 		}
-		// remove last char \n
+		// remove last char \n, line numbers are added by the views
 		// FIXME might need a check for some files last line? could crash...
-		strline := fmt.Sprintf("%7d: %s", linecount, line[:len(line)-1])
-
-		// append to buffer
-		newfile.filebuffer.Addline(linecount, strline)
+		newfile.filebuffer.Addline(linecount, string(line[:len(line)-1]))
 
 		// now we are done, push up linenumber
 		linecount++
@@ -7,10 +7,15 @@ package main
 	it will be called for each screen coordinate (valid in text coordinates and in text coordinate)
 	to deliver characters from top left to lover right
 
+	in front of each line, a gutter shows line number, number of
+	instructions and blocks generated for the line and if they are
+	vector code, lines without code are dimmed.
+
 		(c) Holger Berger 2018
 */
 
 import (
+	"fmt"
 	"regexp"
 
 	"github.com/rthornton128/goncurses"
@@ -18,11 +23,18 @@ import (
 
 // SourceModel implements PanelModel to allow a viewer to get characters and attributes of certain coordinates in file
 type SourceModel struct {
-	sourcefile *Sourcefile // reference to prepared data
-	file       *FileBuffer // reference to underleying data
-	lastline   string      // caching: buffer last line
-	lastlinenr int         // caching: buffer number of last line
-	lastcolor  int16       // caching: color number of last call
+	sourcefile *Sourcefile        // reference to prepared data
+	file       *FileBuffer        // reference to underleying data
+	lastline   string             // caching: buffer last line
+	lastlinenr int                // caching: buffer number of last line
+	lastcolors []int16            // caching: colors of last line
+	lang       int                // language for syntax highlighting
+	colors     [][]int16          // caching: colors of lines highlighted so far, indexed by line number
+	state      int                // lexer state at end of last highlighted line
+	lastgutter string             // caching: gutter of last line
+	lastfacts  *LineFacts         // caching: facts of last line
+	facts      map[int]*LineFacts // code generation facts of the lines
+	factsfile  *AssemblerFile     // assembler file the facts are from
 }
 
 // NewSourceModel creates a model for the view into an sourcefile
@@ -30,60 +42,105 @@ func NewSourceModel(sourcefile *Sourcefile) *SourceModel {
 	var na SourceModel
 	na.sourcefile = sourcefile
 	na.file = sourcefile.filebuffer
+	na.lang = sourcelanguage(na.file.name)
+	na.colors = make([][]int16, 1, 1024) // line 0 is unused
 	return &na
 }
 
+// linecolors returns the colors of a line, highlighting all lines before if not yet done
+func (a *SourceModel) linecolors(y int) []int16 {
+	for l := len(a.colors); l <= y && l <= a.GetNrLines(); l++ {
+		colors, state := highlightline(a.lang, a.file.GetLine(l), a.state)
+		a.colors = append(a.colors, colors)
+		a.state = state
+	}
+	if y < len(a.colors) {
+		return a.colors[y]
+	}
+	return nil
+}
+
+// linefacts returns the facts of a line, nil if no code was generated for it
+func (a *SourceModel) linefacts(y int) *LineFacts {
+	// the assembler file changes when comparing two files
+	if a.factsfile != assemblerfile {
+		a.facts = assemblerfile.linefacts(a.file.name)
+		a.factsfile = assemblerfile
+	}
+	return a.facts[y]
+}
+
+// gutter returns the gutter of a line, only the line number for files the assembler file has no code for
+func (a *SourceModel) gutter(y int) string {
+	facts := a.linefacts(y)
+	if len(a.facts) == 0 {
+		return fmt.Sprintf("%7d: ", y)
+	}
+	return facts.gutter(y)
+}
+
 // GetCell returns character, color and attribute for a given coordinate in file coordinates, (first line = 1)
-func (a SourceModel) GetCell(x, y int) (rune, int16, goncurses.Char) {
+func (a *SourceModel) GetCell(x, y int) (rune, int16, goncurses.Char) {
 	if y != a.lastlinenr || a.lastlinenr == 0 {
 		a.lastline = a.file.GetLine(y)
 		a.lastlinenr = y
-
-		// FIXME make those color constants names constants
-
-		a.lastcolor = 1
-
+		a.lastcolors = a.linecolors(y)
+		a.lastgutter = a.gutter(y)
+		a.lastfacts = a.linefacts(y)
+	}
+	var attr goncurses.Char
+	if a.lastfacts == nil && len(a.facts) > 0 {
+		attr = goncurses.A_DIM // no code for this line
+	}
+	if x < len(a.lastgutter) {
+		color := hlNormal
+		if a.lastfacts != nil && a.lastfacts.vector && a.lastgutter[x] == 'V' {
+			color = hlKeyword
+		}
+		return rune(a.lastgutter[x]), color, attr
+	}
+	x -= len(a.lastgutter)
+	if x < len(a.lastcolors) {
+		return rune(a.lastline[x]), a.lastcolors[x], attr
 	}
-	return rune(a.lastline[x]), a.lastcolor, 0
+	return rune(a.lastline[x]), hlNormal, attr
 }
 
 // GetNrLines returns the number of lines in the file
-func (a SourceModel) GetNrLines() int {
-	// FIXME optimize, could be pushed to filebuffer
-	block := a.file.appendin
-	return a.file.lineblocks[block].lastline
+func (a *SourceModel) GetNrLines() int {
+	return a.file.GetNrLines()
 }
 
 // GetLineLen returns the length of the line (file coordinates, first =  1)
-func (a SourceModel) GetLineLen(line int) int {
+func (a *SourceModel) GetLineLen(line int) int {
 	if line <= a.GetNrLines() {
-		return len(a.file.GetLine(line))
+		return len(a.gutter(line)) + len(a.file.GetLine(line))
 	}
 	return 0
 }
 
-// GetLine returns line without anyu processing (tabs expanded)
-func (a SourceModel) GetLine(line int) string {
+// GetLine returns line without anyu processing (tabs expanded) and without gutter
+func (a *SourceModel) GetLine(line int) string {
 	return a.file.GetLine(line)
 }
 
 // GetFilename returns the filename
-func (a SourceModel) GetFilename() string {
+func (a *SourceModel) GetFilename() string {
 	return a.file.name
 }
 
 // GetSymbol returns the global symbol precedding a line
-func (a SourceModel) GetSymbol(line int) string {
+func (a *SourceModel) GetSymbol(line int) string {
 	// this is a dummy
 	return ""
 }
 
 // GetPosition returns filename and position in source for line
-func (a SourceModel) GetPosition(line int) (string, int) {
+func (a *SourceModel) GetPosition(line int) (string, int) {
 	// this is a dummy
 	return "", 0
 }
 
 // SetRegexp is a dummy
-func (a SourceModel) SetRegexp(r1, r2 *regexp.Regexp) {
+func (a *SourceModel) SetRegexp(r1, r2 *regexp.Regexp) {
 }
@@ -0,0 +1,215 @@
+package main
+
+/*
+	spill and reload detection
+
+	stores and loads relative to frame or stack pointer are
+	accesses to stack slots, like
+		st	%s1, -8(,%fp)          (VE)
+		movsd	%xmm0, -24(%rbp)       (x86)
+	each distinct address inside a function is a slot, stores
+	into a slot are spills, loads from it are reloads.
+*/
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StackSlot is a location in the stack frame of a function
+type StackSlot struct {
+	function int    // index into functions
+	id       int    // number of slot inside function, starting with 1
+	name     string // normalized address like -8(%fp)
+	stores   []int  // lines storing into the slot (spills)
+	loads    []int  // lines loading from the slot (reloads)
+}
+
+// StackAccess describes how a line accesses a stack slot
+type StackAccess struct {
+	slot  int  // index into stackslots
+	store bool // line writes to slot
+	load  bool // line reads from slot
+}
+
+var vestackregs = map[string]string{"%fp": "%fp", "%s9": "%fp", "%sp": "%sp", "%s11": "%sp"}
+var x86stackregs = map[string]string{"%rbp": "%rbp", "%ebp": "%rbp", "%rsp": "%rsp", "%esp": "%rsp"}
+
+// stackoperand returns the normalized name of a stack slot if operand addresses one
+func stackoperand(operand string, ve bool) (string, bool) {
+	m, ok := parsememory(operand, ve)
+	if !ok || m.index != "" {
+		return "", false
+	}
+	var base string
+	if ve {
+		base, ok = vestackregs[m.base]
+	} else {
+		base, ok = x86stackregs[m.base]
+	}
+	if !ok {
+		return "", false
+	}
+	if m.disp == "" {
+		return "0(" + base + ")", true
+	}
+	disp, ok := parseint(m.disp)
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("%d(%s)", disp, base), true
+}
+
+// stackaccessof checks if instruction loads or stores a stack slot
+func stackaccessof(ins Instruction, ve bool) (slot string, store, load bool) {
+	if ve {
+		if len(ins.operands) < 2 {
+			return "", false, false
+		}
+		slot, ok := stackoperand(ins.operands[1], true)
+		if !ok {
+			return "", false, false
+		}
+		switch ins.basemnemonic() {
+		case "st", "stu", "stl", "st2b", "st1b":
+			return slot, true, false
+		case "ld", "ldu", "ldl", "ld2b", "ld1b", "dld", "dldu", "dldl":
+			return slot, false, true
+		}
+		return "", false, false
+	}
+
+	if strings.HasPrefix(ins.mnemonic, "lea") || strings.HasPrefix(ins.mnemonic, "nop") {
+		return "", false, false
+	}
+	last := len(ins.operands) - 1
+	for i, op := range ins.operands {
+		s, ok := stackoperand(op, false)
+		if !ok {
+			continue
+		}
+		slot = s
+		if i < last || strings.HasPrefix(ins.mnemonic, "push") || readonlyx86(ins.mnemonic) {
+			load = true
+		} else if strings.HasPrefix(ins.mnemonic, "mov") || strings.HasPrefix(ins.mnemonic, "vmov") ||
+			strings.HasPrefix(ins.mnemonic, "pop") || strings.HasPrefix(ins.mnemonic, "set") {
+			store = true
+		} else {
+			// read-modify-write like addl $1, -4(%rbp)
+			store = true
+			load = true
+		}
+	}
+	return slot, store, load
+}
+
+// readonlyx86 checks for x86 instructions which only read their last operand
+func readonlyx86(mnemonic string) bool {
+	for _, p := range []string{"cmpxchg", "cmpp", "cmpss", "cmpsd", "btc", "btr", "bts"} {
+		if strings.HasPrefix(mnemonic, p) {
+			return false // write their last operand
+		}
+	}
+	for _, p := range []string{"cmp", "test", "ucomis", "comis", "vucomis", "vcomis", "bt", "ptest", "vptest", "vtestp",
+		"call", "jmp", "prefetch"} {
+		if strings.HasPrefix(mnemonic, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// findstackslots collects all stack slots and their accesses, function by function
+func (a *AssemblerFile) findstackslots() {
+	a.stackslots = make([]StackSlot, 0, 256)
+	a.stackaccess = make(map[int]StackAccess)
+	for fi := range a.functions {
+		f := &a.functions[fi]
+		slots := make(map[string]int) // name -> index into stackslots
+		for l := f.start; l <= f.end; l++ {
+			ins, ok := parseInstruction(a.filebuffer.GetLine(l))
+			if !ok {
+				continue
+			}
+			name, store, load := stackaccessof(ins, a.ve)
+			if !store && !load {
+				continue
+			}
+			si, ok := slots[name]
+			if !ok {
+				si = len(a.stackslots)
+				slots[name] = si
+				a.stackslots = append(a.stackslots, StackSlot{function: fi, id: len(slots), name: name})
+			}
+			if store {
+				a.stackslots[si].stores = append(a.stackslots[si].stores, l)
+				f.spills++
+			}
+			if load {
+				a.stackslots[si].loads = append(a.stackslots[si].loads, l)
+				f.reloads++
+			}
+			a.stackaccess[l] = StackAccess{slot: si, store: store, load: load}
+		}
+	}
+}
+
+// spillinloop checks if line is a spill or reload inside of a loop
+func (a *AssemblerFile) spillinloop(line int) bool {
+	if _, ok := a.stackaccess[line]; ok {
+		return a.loopof(line) != -1
+	}
+	return false
+}
+
+// spillof returns the line of the store a reload at line got its value from,
+// this is the closest store before line, or the last store in case of a loop carried value.
+// for a store, the next reload is returned. -1 if there is none.
+func (a *AssemblerFile) spillof(line int) int {
+	access, ok := a.stackaccess[line]
+	if !ok {
+		return -1
+	}
+	slot := a.stackslots[access.slot]
+	if access.load {
+		found := -1
+		for _, s := range slot.stores {
+			if s < line {
+				found = s
+			}
+		}
+		if found == -1 && len(slot.stores) > 0 {
+			found = slot.stores[len(slot.stores)-1]
+			if found == line {
+				found = -1
+			}
+		}
+		return found
+	}
+	for _, l := range slot.loads {
+		if l > line {
+			return l
+		}
+	}
+	return -1
+}
+
+// describestack returns a description of the stack access of a line, or ""
+func (a *AssemblerFile) describestack(line int) string {
+	access, ok := a.stackaccess[line]
+	if !ok {
+		return ""
+	}
+	slot := a.stackslots[access.slot]
+	kind := "spill to"
+	if access.load && access.store {
+		kind = "update of"
+	} else if access.load {
+		kind = "reload from"
+	}
+	desc := fmt.Sprintf("%s stack slot %d %s (%d stores, %d loads)", kind, slot.id, slot.name, len(slot.stores), len(slot.loads))
+	if li := a.loopof(line); li != -1 {
+		desc += " inside loop " + a.loopname(li)
+	}
+	return desc
+}
@@ -0,0 +1,121 @@
+package main
+
+/*
+	tests of the stack slot tracking, and helpers for tests
+	working on assembler files
+*/
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// testfile reads an assembler file given as text
+func testfile(t *testing.T, text string) *AssemblerFile {
+	t.Helper()
+	f, err := ioutil.TempFile("", "veass*.s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(text); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	a, err := NewAssemblerFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+// linewith returns the first line of a file containing text, runs of blanks and tabs compare equal
+func linewith(t *testing.T, a *AssemblerFile, text string) int {
+	t.Helper()
+	text = strings.Join(strings.Fields(text), " ")
+	for l := 1; l < len(a.index); l++ {
+		if strings.Contains(strings.Join(strings.Fields(a.filebuffer.GetLine(l)), " "), text) {
+			return l
+		}
+	}
+	t.Fatalf("no line contains %q", text)
+	return 0
+}
+
+func TestStackAccess(t *testing.T) {
+	tests := []struct {
+		line        string
+		ve          bool
+		slot        string
+		store, load bool
+	}{
+		{"\tmovsd\t%xmm0, -24(%rbp)", false, "-24(%rbp)", true, false},
+		{"\tmovsd\t-24(%rbp), %xmm0", false, "-24(%rbp)", false, true},
+		{"\tmovq\t%rax, 8(%rsp)", false, "8(%rsp)", true, false},
+		{"\tmovl\t(%rsp), %eax", false, "0(%rsp)", false, true},
+		{"\taddl\t$1, -4(%rbp)", false, "-4(%rbp)", true, true},
+		{"\tcmpl\t$9, -4(%rbp)", false, "-4(%rbp)", false, true},
+		{"\tpushq\t-8(%rbp)", false, "-8(%rbp)", false, true},
+		{"\tleaq\t-16(%rbp), %rax", false, "", false, false},
+		{"\tmovq\t8(%rax), %rbx", false, "", false, false},
+		{"\tmovq\t-8(%rbp,%rax,8), %rbx", false, "", false, false},
+		{"\tst\t%s18,-8(,%fp)", true, "-8(%fp)", true, false},
+		{"\tld\t%s18,-8(,%s9)", true, "-8(%fp)", false, true},
+		{"\tldl.sx\t%s1,16(,%sp)", true, "16(%sp)", false, true},
+		{"\tld\t%s1,8(,%s0)", true, "", false, false},
+	}
+	for _, test := range tests {
+		ins, ok := parseInstruction(test.line)
+		if !ok {
+			t.Fatalf("%q is no instruction", test.line)
+		}
+		slot, store, load := stackaccessof(ins, test.ve)
+		if slot != test.slot || store != test.store || load != test.load {
+			t.Errorf("stackaccessof(%q) = %q, %v, %v, want %q, %v, %v", test.line, slot, store, load, test.slot, test.store, test.load)
+		}
+	}
+}
+
+func TestSpills(t *testing.T) {
+	a := testfile(t, `	.text
+	.globl	f
+	.type	f, @function
+f:
+	movl	$0, -4(%rbp)
+.L2:
+	movsd	%xmm0, -24(%rbp)
+	call	g
+	movsd	-24(%rbp), %xmm0
+	addl	$1, -4(%rbp)
+	cmpl	$9, -4(%rbp)
+	jle	.L2
+	ret
+	.size	f, .-f
+`)
+	init := linewith(t, a, "movl")
+	spill := linewith(t, a, "%xmm0, -24(%rbp)")
+	reload := linewith(t, a, "-24(%rbp), %xmm0")
+	update := linewith(t, a, "addl")
+
+	if len(a.stackslots) != 2 {
+		t.Fatalf("%d stack slots, want 2", len(a.stackslots))
+	}
+	if f := a.functions[0]; f.spills != 3 || f.reloads != 3 {
+		t.Errorf("%d spills and %d reloads, want 3 and 3", f.spills, f.reloads)
+	}
+	if l := a.spillof(reload); l != spill {
+		t.Errorf("reload in line %d loads from line %d, want %d", reload, l, spill)
+	}
+	if l := a.spillof(spill); l != reload {
+		t.Errorf("spill in line %d is reloaded in line %d, want %d", spill, l, reload)
+	}
+	if a.spillinloop(init) || !a.spillinloop(spill) || !a.spillinloop(update) {
+		t.Errorf("spills in loop: line %d %v, line %d %v, line %d %v, want false, true, true",
+			init, a.spillinloop(init), spill, a.spillinloop(spill), update, a.spillinloop(update))
+	}
+	if desc := a.describestack(update); !strings.HasPrefix(desc, "update of stack slot 1 -4(%rbp) (2 stores, 2 loads) inside loop .L2") {
+		t.Errorf("describestack = %q", desc)
+	}
+}
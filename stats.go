@@ -0,0 +1,176 @@
+package main
+
+/*
+	batch statistics
+
+		veass stats [--format table|json|csv] file.s [file.s ...]
+
+	prints for each global symbol of the files the number of
+	instructions, the instruction mix by class, the share of
+	vector instructions, branches, loops and the range of
+	source lines the code was produced from.
+*/
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+)
+
+// SymbolStats are the statistics of one global symbol
+type SymbolStats struct {
+	File         string         `json:"file"`
+	Symbol       string         `json:"symbol"`
+	Name         string         `json:"name"` // demangled symbol
+	Instructions int            `json:"instructions"`
+	Mix          map[string]int `json:"mix"`
+	VectorRatio  float64        `json:"vector_ratio"`
+	Branches     int            `json:"branches"`
+	Loops        int            `json:"loops"`
+	Source       string         `json:"source"`
+	FirstLine    int            `json:"first_line"`
+	LastLine     int            `json:"last_line"`
+}
+
+// symbolstats computes the statistics of a function
+func (a *AssemblerFile) symbolstats(fi int) SymbolStats {
+	f := a.functions[fi]
+	var classes [nrclasses]int
+	vector := 0
+	for l := f.start; l <= f.end; l++ {
+		ins, ok := parseInstruction(a.filebuffer.GetLine(l))
+		if !ok {
+			continue
+		}
+		class := a.classify(ins)
+		classes[class]++
+		if isvectorclass(class) {
+			vector++
+		}
+	}
+	source, first, last := a.sourcerange(f.start, f.end)
+	s := SymbolStats{Symbol: f.name, Name: symbolname(f.name), Instructions: f.instructions, Mix: make(map[string]int),
+		Branches: classes[classBranch], Loops: len(f.loops), FirstLine: first, LastLine: last}
+	for c := 0; c < nrclasses; c++ {
+		s.Mix[classnames[c]] = classes[c]
+	}
+	if f.instructions > 0 {
+		s.VectorRatio = float64(vector) / float64(f.instructions)
+	}
+	s.Source = source
+	return s
+}
+
+// sourcerange returns file, first and last source line of the instructions in a range of lines,
+// only the file of the first located instruction is taken into account
+func (a *AssemblerFile) sourcerange(from, to int) (string, int, int) {
+	fileid := -1
+	first, last := 0, 0
+	for l := from; l <= to; l++ {
+		if _, ok := parseInstruction(a.filebuffer.GetLine(l)); !ok {
+			continue
+		}
+		loc := a.index[l].loc
+		if loc.linenr == 0 || fileid != -1 && loc.fileid != fileid {
+			continue
+		}
+		fileid = loc.fileid
+		if first == 0 || loc.linenr < first {
+			first = loc.linenr
+		}
+		if loc.linenr > last {
+			last = loc.linenr
+		}
+	}
+	if fileid >= 0 && fileid < len(a.filenametable) {
+		return a.filenametable[fileid], first, last
+	}
+	return "", first, last
+}
+
+// writestatstable prints stats as aligned table, branches are part of the mix
+func writestatstable(w io.Writer, stats []SymbolStats) {
+	fmt.Fprintf(w, "%-20s %-24s %6s", "file", "symbol", "instr")
+	for c := 0; c < nrclasses; c++ {
+		fmt.Fprintf(w, " %*s", maxi(len(classnames[c]), 5), classnames[c])
+	}
+	fmt.Fprintf(w, " %5s %5s  %s\n", "vec%", "loops", "source")
+	for _, s := range stats {
+		fmt.Fprintf(w, "%-20s %-24s %6d", s.File, s.Name, s.Instructions)
+		for c := 0; c < nrclasses; c++ {
+			fmt.Fprintf(w, " %*d", maxi(len(classnames[c]), 5), s.Mix[classnames[c]])
+		}
+		fmt.Fprintf(w, " %4.0f%% %5d  %s:%d-%d\n", 100*s.VectorRatio, s.Loops, s.Source, s.FirstLine, s.LastLine)
+	}
+}
+
+// writestatscsv prints stats as CSV with header
+func writestatscsv(w io.Writer, stats []SymbolStats) error {
+	cw := csv.NewWriter(w)
+	header := []string{"file", "symbol", "instructions"}
+	header = append(header, classnames[:]...)
+	header = append(header, "vector_ratio", "branches", "loops", "source", "first_line", "last_line", "name")
+	cw.Write(header)
+	for _, s := range stats {
+		record := []string{s.File, s.Symbol, strconv.Itoa(s.Instructions)}
+		for c := 0; c < nrclasses; c++ {
+			record = append(record, strconv.Itoa(s.Mix[classnames[c]]))
+		}
+		record = append(record, strconv.FormatFloat(s.VectorRatio, 'f', 3, 64), strconv.Itoa(s.Branches),
+			strconv.Itoa(s.Loops), s.Source, strconv.Itoa(s.FirstLine), strconv.Itoa(s.LastLine), s.Name)
+		cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// runstats implements the stats command, returns the exit code
+func runstats(args []string, format string) int {
+	if len(args) == 0 {
+		fmt.Println("usage: veass stats [--format table|json|csv] <file.s> [file.s ...]")
+		return 2
+	}
+	switch format {
+	case "table", "json", "csv":
+	default:
+		fmt.Fprintf(os.Stderr, "unknown format %q, use table, json or csv\n", format)
+		return 2
+	}
+	progress = ioutil.Discard
+
+	stats := make([]SymbolStats, 0, 64)
+	for _, filename := range args {
+		a, err := NewAssemblerFile(filename)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		for fi := range a.functions {
+			s := a.symbolstats(fi)
+			s.File = filename
+			stats = append(stats, s)
+		}
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(stats); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	case "csv":
+		if err := writestatscsv(os.Stdout, stats); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	case "table":
+		writestatstable(os.Stdout, stats)
+	}
+	return 0
+}
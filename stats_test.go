@@ -0,0 +1,85 @@
+package main
+
+/*
+	tests of the statistics of the stats command
+*/
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+)
+
+const statssample = `	.file	"s.c"
+	.text
+	.globl	sum
+	.type	sum, @function
+sum:
+	.file 1 "s.c"
+	.loc 1 3 0
+	vxorpd	%xmm0, %xmm0, %xmm0
+	xorl	%eax, %eax
+.L2:
+	.loc 1 4 0
+	vaddpd	(%rdi,%rax), %ymm0, %ymm0
+	addq	$32, %rax
+	cmpq	%rsi, %rax
+	jne	.L2
+	.loc 1 5 0
+	ret
+	.size	sum, .-sum
+`
+
+func TestStatsOutput(t *testing.T) {
+	a := testfile(t, statssample)
+	s := a.symbolstats(0)
+	s.File = "s.s"
+	if s.Symbol != "sum" || s.Instructions != 7 || s.Branches != 2 || s.Loops != 1 || s.Source != "s.c" || s.FirstLine != 3 || s.LastLine != 5 {
+		t.Errorf("stats %+v", s)
+	}
+	if s.Mix["vector"] != 1 || s.Mix["vector-load"] != 1 {
+		t.Errorf("mix %v, want 1 vector and 1 vector-load", s.Mix)
+	}
+
+	var buf bytes.Buffer
+	if err := writestatscsv(&buf, []SymbolStats{s}); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil || len(records) != 2 {
+		t.Fatalf("CSV %q, %v", buf.String(), err)
+	}
+	want := map[string]string{"file": "s.s", "symbol": "sum", "instructions": "7", "branch": "2", "vector_ratio": "0.286",
+		"loops": "1", "source": "s.c", "first_line": "3", "last_line": "5"}
+	for i, column := range records[0] {
+		if w, ok := want[column]; ok && records[1][i] != w {
+			t.Errorf("CSV column %s = %q, want %q", column, records[1][i], w)
+		}
+		delete(want, column)
+	}
+	if len(want) > 0 {
+		t.Errorf("CSV columns missing: %v", want)
+	}
+
+	buf.Reset()
+	if err := json.NewEncoder(&buf).Encode([]SymbolStats{s}); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded) != 1 {
+		t.Fatalf("JSON %q, %v", buf.String(), err)
+	}
+	if d := decoded[0]; d["symbol"] != "sum" || d["instructions"] != 7.0 || d["loops"] != 1.0 ||
+		d["mix"].(map[string]interface{})["vector"] != 1.0 {
+		t.Errorf("JSON %v", d)
+	}
+}
+
+func TestStatsFormat(t *testing.T) {
+	for _, format := range []string{"xml", "", "JSON"} {
+		if exit := runstats([]string{"s.s"}, format); exit != 2 {
+			t.Errorf("stats with format %q exits with %d, want 2", format, exit)
+		}
+	}
+}
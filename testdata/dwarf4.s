@@ -0,0 +1,345 @@
+	.file	"t.c"
+	.text
+.Ltext0:
+	.cfi_sections	.debug_frame
+	.file 1 "t.c"
+	.p2align 4
+	.globl	f
+	.type	f, @function
+f:
+.LFB0:
+	.loc 1 3 1
+	.cfi_startproc
+.LVL0:
+	.loc 1 4 2
+	.loc 1 4 15 is_stmt 0
+	pxor	%xmm1, %xmm1
+	.loc 1 3 1
+	subq	$24, %rsp
+	.cfi_def_cfa_offset 32
+	.loc 1 4 15
+	cvtsi2sdl	%edi, %xmm1
+	.loc 1 4 9
+	mulsd	%xmm0, %xmm1
+.LVL1:
+	.loc 1 5 2 is_stmt 1
+	.loc 1 5 9 is_stmt 0
+	movapd	%xmm1, %xmm0
+.LVL2:
+	movsd	%xmm1, 8(%rsp)
+	call	g@PLT
+.LVL3:
+	.loc 1 5 14
+	movsd	8(%rsp), %xmm1
+	.loc 1 6 1
+	addq	$24, %rsp
+	.cfi_def_cfa_offset 8
+	.loc 1 5 14
+	addsd	%xmm1, %xmm0
+	.loc 1 6 1
+	ret
+	.cfi_endproc
+.LFE0:
+	.size	f, .-f
+.Letext0:
+	.section	.debug_info,"",@progbits
+.Ldebug_info0:
+	.long	0xae
+	.value	0x4
+	.long	.Ldebug_abbrev0
+	.byte	0x8
+	.uleb128 0x1
+	.long	.LASF1
+	.byte	0xc
+	.string	"t.c"
+	.long	.LASF2
+	.quad	.Ltext0
+	.quad	.Letext0-.Ltext0
+	.long	.Ldebug_line0
+	.uleb128 0x2
+	.byte	0x8
+	.byte	0x4
+	.long	.LASF0
+	.uleb128 0x3
+	.string	"g"
+	.byte	0x1
+	.byte	0x1
+	.byte	0x8
+	.long	0x2d
+	.long	0x48
+	.uleb128 0x4
+	.long	0x2d
+	.byte	0
+	.uleb128 0x5
+	.string	"f"
+	.byte	0x1
+	.byte	0x2
+	.byte	0x8
+	.long	0x2d
+	.quad	.LFB0
+	.quad	.LFE0-.LFB0
+	.uleb128 0x1
+	.byte	0x9c
+	.long	0xaa
+	.uleb128 0x6
+	.string	"n"
+	.byte	0x1
+	.byte	0x2
+	.byte	0xe
+	.long	0xaa
+	.long	.LLST0
+	.uleb128 0x6
+	.string	"a"
+	.byte	0x1
+	.byte	0x2
+	.byte	0x18
+	.long	0x2d
+	.long	.LLST1
+	.uleb128 0x7
+	.string	"s"
+	.byte	0x1
+	.byte	0x4
+	.byte	0x9
+	.long	0x2d
+	.long	.LLST2
+	.uleb128 0x8
+	.quad	.LVL3
+	.long	0x34
+	.uleb128 0x9
+	.uleb128 0x1
+	.byte	0x61
+	.uleb128 0x5
+	.byte	0x91
+	.sleb128 -24
+	.byte	0xf6
+	.byte	0x8
+	.uleb128 0x2d
+	.byte	0
+	.byte	0
+	.uleb128 0xa
+	.byte	0x4
+	.byte	0x5
+	.string	"int"
+	.byte	0
+	.section	.debug_abbrev,"",@progbits
+.Ldebug_abbrev0:
+	.uleb128 0x1
+	.uleb128 0x11
+	.byte	0x1
+	.uleb128 0x25
+	.uleb128 0xe
+	.uleb128 0x13
+	.uleb128 0xb
+	.uleb128 0x3
+	.uleb128 0x8
+	.uleb128 0x1b
+	.uleb128 0xe
+	.uleb128 0x11
+	.uleb128 0x1
+	.uleb128 0x12
+	.uleb128 0x7
+	.uleb128 0x10
+	.uleb128 0x17
+	.byte	0
+	.byte	0
+	.uleb128 0x2
+	.uleb128 0x24
+	.byte	0
+	.uleb128 0xb
+	.uleb128 0xb
+	.uleb128 0x3e
+	.uleb128 0xb
+	.uleb128 0x3
+	.uleb128 0xe
+	.byte	0
+	.byte	0
+	.uleb128 0x3
+	.uleb128 0x2e
+	.byte	0x1
+	.uleb128 0x3f
+	.uleb128 0x19
+	.uleb128 0x3
+	.uleb128 0x8
+	.uleb128 0x3a
+	.uleb128 0xb
+	.uleb128 0x3b
+	.uleb128 0xb
+	.uleb128 0x39
+	.uleb128 0xb
+	.uleb128 0x27
+	.uleb128 0x19
+	.uleb128 0x49
+	.uleb128 0x13
+	.uleb128 0x3c
+	.uleb128 0x19
+	.uleb128 0x1
+	.uleb128 0x13
+	.byte	0
+	.byte	0
+	.uleb128 0x4
+	.uleb128 0x5
+	.byte	0
+	.uleb128 0x49
+	.uleb128 0x13
+	.byte	0
+	.byte	0
+	.uleb128 0x5
+	.uleb128 0x2e
+	.byte	0x1
+	.uleb128 0x3f
+	.uleb128 0x19
+	.uleb128 0x3
+	.uleb128 0x8
+	.uleb128 0x3a
+	.uleb128 0xb
+	.uleb128 0x3b
+	.uleb128 0xb
+	.uleb128 0x39
+	.uleb128 0xb
+	.uleb128 0x27
+	.uleb128 0x19
+	.uleb128 0x49
+	.uleb128 0x13
+	.uleb128 0x11
+	.uleb128 0x1
+	.uleb128 0x12
+	.uleb128 0x7
+	.uleb128 0x40
+	.uleb128 0x18
+	.uleb128 0x2117
+	.uleb128 0x19
+	.uleb128 0x1
+	.uleb128 0x13
+	.byte	0
+	.byte	0
+	.uleb128 0x6
+	.uleb128 0x5
+	.byte	0
+	.uleb128 0x3
+	.uleb128 0x8
+	.uleb128 0x3a
+	.uleb128 0xb
+	.uleb128 0x3b
+	.uleb128 0xb
+	.uleb128 0x39
+	.uleb128 0xb
+	.uleb128 0x49
+	.uleb128 0x13
+	.uleb128 0x2
+	.uleb128 0x17
+	.byte	0
+	.byte	0
+	.uleb128 0x7
+	.uleb128 0x34
+	.byte	0
+	.uleb128 0x3
+	.uleb128 0x8
+	.uleb128 0x3a
+	.uleb128 0xb
+	.uleb128 0x3b
+	.uleb128 0xb
+	.uleb128 0x39
+	.uleb128 0xb
+	.uleb128 0x49
+	.uleb128 0x13
+	.uleb128 0x2
+	.uleb128 0x17
+	.byte	0
+	.byte	0
+	.uleb128 0x8
+	.uleb128 0x4109
+	.byte	0x1
+	.uleb128 0x11
+	.uleb128 0x1
+	.uleb128 0x31
+	.uleb128 0x13
+	.byte	0
+	.byte	0
+	.uleb128 0x9
+	.uleb128 0x410a
+	.byte	0
+	.uleb128 0x2
+	.uleb128 0x18
+	.uleb128 0x2111
+	.uleb128 0x18
+	.byte	0
+	.byte	0
+	.uleb128 0xa
+	.uleb128 0x24
+	.byte	0
+	.uleb128 0xb
+	.uleb128 0xb
+	.uleb128 0x3e
+	.uleb128 0xb
+	.uleb128 0x3
+	.uleb128 0x8
+	.byte	0
+	.byte	0
+	.byte	0
+	.section	.debug_loc,"",@progbits
+.Ldebug_loc0:
+.LLST0:
+	.quad	.LVL0-.Ltext0
+	.quad	.LVL3-1-.Ltext0
+	.value	0x1
+	.byte	0x55
+	.quad	.LVL3-1-.Ltext0
+	.quad	.LFE0-.Ltext0
+	.value	0x4
+	.byte	0xf3
+	.uleb128 0x1
+	.byte	0x55
+	.byte	0x9f
+	.quad	0
+	.quad	0
+.LLST1:
+	.quad	.LVL0-.Ltext0
+	.quad	.LVL2-.Ltext0
+	.value	0x1
+	.byte	0x61
+	.quad	.LVL2-.Ltext0
+	.quad	.LFE0-.Ltext0
+	.value	0x6
+	.byte	0xf3
+	.uleb128 0x3
+	.byte	0xf5
+	.uleb128 0x11
+	.uleb128 0x2d
+	.byte	0x9f
+	.quad	0
+	.quad	0
+.LLST2:
+	.quad	.LVL1-.Ltext0
+	.quad	.LVL3-1-.Ltext0
+	.value	0x1
+	.byte	0x62
+	.quad	.LVL3-1-.Ltext0
+	.quad	.LFE0-.Ltext0
+	.value	0x2
+	.byte	0x91
+	.sleb128 -24
+	.quad	0
+	.quad	0
+	.section	.debug_aranges,"",@progbits
+	.long	0x2c
+	.value	0x2
+	.long	.Ldebug_info0
+	.byte	0x8
+	.byte	0
+	.value	0
+	.value	0
+	.quad	.Ltext0
+	.quad	.Letext0-.Ltext0
+	.quad	0
+	.quad	0
+	.section	.debug_line,"",@progbits
+.Ldebug_line0:
+	.section	.debug_str,"MS",@progbits,1
+.LASF1:
+	.string	"GNU C17 12.2.0 -mtune=generic -march=x86-64 -g -gdwarf-4 -gno-variable-location-views -O2 -fno-asynchronous-unwind-tables"
+.LASF0:
+	.string	"double"
+.LASF2:
+	.string	"/home/user/src"
+	.ident	"GCC: (Debian 12.2.0-14+deb12u1) 12.2.0"
+	.section	.note.GNU-stack,"",@progbits
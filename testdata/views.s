@@ -0,0 +1,377 @@
+	.file	"t.c"
+	.text
+.Ltext0:
+	.cfi_sections	.debug_frame
+	.file 0 "/home/user/src" "t.c"
+	.p2align 4
+	.globl	f
+	.type	f, @function
+f:
+.LVL0:
+.LFB0:
+	.file 1 "t.c"
+	.loc 1 3 1 view -0
+	.cfi_startproc
+	.loc 1 4 2 view .LVU1
+	.loc 1 4 15 is_stmt 0 view .LVU2
+	pxor	%xmm1, %xmm1
+	.loc 1 3 1 view .LVU3
+	subq	$24, %rsp
+	.cfi_def_cfa_offset 32
+	.loc 1 4 15 view .LVU4
+	cvtsi2sdl	%edi, %xmm1
+	.loc 1 4 9 view .LVU5
+	mulsd	%xmm0, %xmm1
+.LVL1:
+	.loc 1 5 2 is_stmt 1 view .LVU6
+	.loc 1 5 9 is_stmt 0 view .LVU7
+	movapd	%xmm1, %xmm0
+.LVL2:
+	.loc 1 5 9 view .LVU8
+	movsd	%xmm1, 8(%rsp)
+	call	g@PLT
+.LVL3:
+	.loc 1 5 14 view .LVU9
+	movsd	8(%rsp), %xmm1
+	.loc 1 6 1 view .LVU10
+	addq	$24, %rsp
+	.cfi_def_cfa_offset 8
+	.loc 1 5 14 view .LVU11
+	addsd	%xmm1, %xmm0
+	.loc 1 6 1 view .LVU12
+	ret
+	.cfi_endproc
+.LFE0:
+	.size	f, .-f
+.Letext0:
+	.section	.debug_info,"",@progbits
+.Ldebug_info0:
+	.long	0xab
+	.value	0x5
+	.byte	0x1
+	.byte	0x8
+	.long	.Ldebug_abbrev0
+	.uleb128 0x2
+	.long	.LASF3
+	.byte	0x1d
+	.long	.LASF0
+	.long	.LASF1
+	.quad	.Ltext0
+	.quad	.Letext0-.Ltext0
+	.long	.Ldebug_line0
+	.uleb128 0x3
+	.byte	0x8
+	.byte	0x4
+	.long	.LASF2
+	.uleb128 0x4
+	.string	"g"
+	.byte	0x1
+	.byte	0x1
+	.byte	0x8
+	.long	0x2e
+	.long	0x49
+	.uleb128 0x5
+	.long	0x2e
+	.byte	0
+	.uleb128 0x6
+	.string	"f"
+	.byte	0x1
+	.byte	0x2
+	.byte	0x8
+	.long	0x2e
+	.quad	.LFB0
+	.quad	.LFE0-.LFB0
+	.uleb128 0x1
+	.byte	0x9c
+	.long	0xa7
+	.uleb128 0x1
+	.string	"n"
+	.byte	0xe
+	.long	0xa7
+	.long	.LLST0
+	.uleb128 0x1
+	.string	"a"
+	.byte	0x18
+	.long	0x2e
+	.long	.LLST1
+	.uleb128 0x7
+	.string	"s"
+	.byte	0x1
+	.byte	0x4
+	.byte	0x9
+	.long	0x2e
+	.long	.LLST2
+	.uleb128 0x8
+	.quad	.LVL3
+	.long	0x35
+	.uleb128 0x9
+	.uleb128 0x1
+	.byte	0x61
+	.uleb128 0x5
+	.byte	0x91
+	.sleb128 -24
+	.byte	0xa6
+	.byte	0x8
+	.uleb128 0x2e
+	.byte	0
+	.byte	0
+	.uleb128 0xa
+	.byte	0x4
+	.byte	0x5
+	.string	"int"
+	.byte	0
+	.section	.debug_abbrev,"",@progbits
+.Ldebug_abbrev0:
+	.uleb128 0x1
+	.uleb128 0x5
+	.byte	0
+	.uleb128 0x3
+	.uleb128 0x8
+	.uleb128 0x3a
+	.uleb128 0x21
+	.sleb128 1
+	.uleb128 0x3b
+	.uleb128 0x21
+	.sleb128 2
+	.uleb128 0x39
+	.uleb128 0xb
+	.uleb128 0x49
+	.uleb128 0x13
+	.uleb128 0x2
+	.uleb128 0x17
+	.byte	0
+	.byte	0
+	.uleb128 0x2
+	.uleb128 0x11
+	.byte	0x1
+	.uleb128 0x25
+	.uleb128 0xe
+	.uleb128 0x13
+	.uleb128 0xb
+	.uleb128 0x3
+	.uleb128 0x1f
+	.uleb128 0x1b
+	.uleb128 0x1f
+	.uleb128 0x11
+	.uleb128 0x1
+	.uleb128 0x12
+	.uleb128 0x7
+	.uleb128 0x10
+	.uleb128 0x17
+	.byte	0
+	.byte	0
+	.uleb128 0x3
+	.uleb128 0x24
+	.byte	0
+	.uleb128 0xb
+	.uleb128 0xb
+	.uleb128 0x3e
+	.uleb128 0xb
+	.uleb128 0x3
+	.uleb128 0xe
+	.byte	0
+	.byte	0
+	.uleb128 0x4
+	.uleb128 0x2e
+	.byte	0x1
+	.uleb128 0x3f
+	.uleb128 0x19
+	.uleb128 0x3
+	.uleb128 0x8
+	.uleb128 0x3a
+	.uleb128 0xb
+	.uleb128 0x3b
+	.uleb128 0xb
+	.uleb128 0x39
+	.uleb128 0xb
+	.uleb128 0x27
+	.uleb128 0x19
+	.uleb128 0x49
+	.uleb128 0x13
+	.uleb128 0x3c
+	.uleb128 0x19
+	.uleb128 0x1
+	.uleb128 0x13
+	.byte	0
+	.byte	0
+	.uleb128 0x5
+	.uleb128 0x5
+	.byte	0
+	.uleb128 0x49
+	.uleb128 0x13
+	.byte	0
+	.byte	0
+	.uleb128 0x6
+	.uleb128 0x2e
+	.byte	0x1
+	.uleb128 0x3f
+	.uleb128 0x19
+	.uleb128 0x3
+	.uleb128 0x8
+	.uleb128 0x3a
+	.uleb128 0xb
+	.uleb128 0x3b
+	.uleb128 0xb
+	.uleb128 0x39
+	.uleb128 0xb
+	.uleb128 0x27
+	.uleb128 0x19
+	.uleb128 0x49
+	.uleb128 0x13
+	.uleb128 0x11
+	.uleb128 0x1
+	.uleb128 0x12
+	.uleb128 0x7
+	.uleb128 0x40
+	.uleb128 0x18
+	.uleb128 0x7a
+	.uleb128 0x19
+	.uleb128 0x1
+	.uleb128 0x13
+	.byte	0
+	.byte	0
+	.uleb128 0x7
+	.uleb128 0x34
+	.byte	0
+	.uleb128 0x3
+	.uleb128 0x8
+	.uleb128 0x3a
+	.uleb128 0xb
+	.uleb128 0x3b
+	.uleb128 0xb
+	.uleb128 0x39
+	.uleb128 0xb
+	.uleb128 0x49
+	.uleb128 0x13
+	.uleb128 0x2
+	.uleb128 0x17
+	.byte	0
+	.byte	0
+	.uleb128 0x8
+	.uleb128 0x48
+	.byte	0x1
+	.uleb128 0x7d
+	.uleb128 0x1
+	.uleb128 0x7f
+	.uleb128 0x13
+	.byte	0
+	.byte	0
+	.uleb128 0x9
+	.uleb128 0x49
+	.byte	0
+	.uleb128 0x2
+	.uleb128 0x18
+	.uleb128 0x7e
+	.uleb128 0x18
+	.byte	0
+	.byte	0
+	.uleb128 0xa
+	.uleb128 0x24
+	.byte	0
+	.uleb128 0xb
+	.uleb128 0xb
+	.uleb128 0x3e
+	.uleb128 0xb
+	.uleb128 0x3
+	.uleb128 0x8
+	.byte	0
+	.byte	0
+	.byte	0
+	.section	.debug_loclists,"",@progbits
+	.long	.Ldebug_loc3-.Ldebug_loc2
+.Ldebug_loc2:
+	.value	0x5
+	.byte	0x8
+	.byte	0
+	.long	0
+.Ldebug_loc0:
+.LLST0:
+	.byte	0x9
+	.uleb128 0
+	.uleb128 .LVU9
+	.byte	0x4
+	.uleb128 .LVL0-.Ltext0
+	.uleb128 .LVL3-1-.Ltext0
+	.uleb128 0x1
+	.byte	0x55
+	.byte	0x9
+	.uleb128 .LVU9
+	.uleb128 0
+	.byte	0x4
+	.uleb128 .LVL3-1-.Ltext0
+	.uleb128 .LFE0-.Ltext0
+	.uleb128 0x4
+	.byte	0xa3
+	.uleb128 0x1
+	.byte	0x55
+	.byte	0x9f
+	.byte	0
+.LLST1:
+	.byte	0x9
+	.uleb128 0
+	.uleb128 .LVU8
+	.byte	0x4
+	.uleb128 .LVL0-.Ltext0
+	.uleb128 .LVL2-.Ltext0
+	.uleb128 0x1
+	.byte	0x61
+	.byte	0x9
+	.uleb128 .LVU8
+	.uleb128 0
+	.byte	0x4
+	.uleb128 .LVL2-.Ltext0
+	.uleb128 .LFE0-.Ltext0
+	.uleb128 0x6
+	.byte	0xa3
+	.uleb128 0x3
+	.byte	0xa5
+	.uleb128 0x11
+	.uleb128 0x2e
+	.byte	0x9f
+	.byte	0
+.LLST2:
+	.byte	0x9
+	.uleb128 .LVU6
+	.uleb128 .LVU9
+	.byte	0x4
+	.uleb128 .LVL1-.Ltext0
+	.uleb128 .LVL3-1-.Ltext0
+	.uleb128 0x1
+	.byte	0x62
+	.byte	0x9
+	.uleb128 .LVU9
+	.uleb128 0
+	.byte	0x4
+	.uleb128 .LVL3-1-.Ltext0
+	.uleb128 .LFE0-.Ltext0
+	.uleb128 0x2
+	.byte	0x91
+	.sleb128 -24
+	.byte	0
+.Ldebug_loc3:
+	.section	.debug_aranges,"",@progbits
+	.long	0x2c
+	.value	0x2
+	.long	.Ldebug_info0
+	.byte	0x8
+	.byte	0
+	.value	0
+	.value	0
+	.quad	.Ltext0
+	.quad	.Letext0-.Ltext0
+	.quad	0
+	.quad	0
+	.section	.debug_line,"",@progbits
+.Ldebug_line0:
+	.section	.debug_str,"MS",@progbits,1
+.LASF2:
+	.string	"double"
+.LASF3:
+	.string	"GNU C17 12.2.0 -mtune=generic -march=x86-64 -g -gvariable-location-views=incompat5 -O2 -fno-asynchronous-unwind-tables"
+	.section	.debug_line_str,"MS",@progbits,1
+.LASF1:
+	.string	"/home/user/src"
+.LASF0:
+	.string	"t.c"
+	.ident	"GCC: (Debian 12.2.0-14+deb12u1) 12.2.0"
+	.section	.note.GNU-stack,"",@progbits
@@ -0,0 +1,415 @@
+package main
+
+/*
+	static throughput and latency estimate
+
+	a table with latency, reciprocal throughput and ports of
+	instructions is read for VE and some x86 microarchitectures,
+	see timingtables.go for the format. the built in tables can
+	be extended or overwritten with a file given by --timings.
+
+	the model runs over a basic block or a marked region:
+	- x86 is modeled out of order with unlimited window, which gives
+	  the latency bound, and the port pressure gives the throughput bound.
+	- VE is modeled in order, an instruction issues when its inputs
+	  are ready and a unit it can run on is free. vector instructions
+	  occupy their unit in proportion to the vector length.
+	if the region is a loop, several iterations are simulated and the
+	cycles per iteration are taken from the last ones, this takes
+	loop carried dependencies into account.
+	register dependencies and dependencies through stack slots are
+	followed, other memory dependencies are ignored.
+*/
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const simiterations = 8 // iterations simulated for loops
+const x86issuewidth = 4 // instructions issued per cycle on x86
+const defaultuarch = "skylake"
+
+// Timing is latency and throughput of an instruction
+type Timing struct {
+	pattern    string  // mnemonic, can contain * as wildcard
+	latency    float64 // cycles until result is available
+	throughput float64 // reciprocal throughput, cycles a port or unit is busy
+	ports      string  // ports (x86) or units (VE) the instruction can use, one character each
+}
+
+// TimingTable is the table for one architecture, first matching pattern wins
+type TimingTable struct {
+	name    string
+	entries []Timing
+	cache   map[string]Timing
+}
+
+var timingtables = make(map[string]*TimingTable)
+
+// readtimings reads tables in text format, entries for existing tables are put in front
+func readtimings(r io.Reader) error {
+	var table *TimingTable
+	added := make(map[string][]Timing)
+	scanner := bufio.NewScanner(r)
+	linenr := 0
+	for scanner.Scan() {
+		linenr++
+		line := scanner.Text()
+		if pos := strings.IndexByte(line, '#'); pos != -1 {
+			line = line[:pos]
+		}
+		flds := strings.Fields(line)
+		if len(flds) == 0 {
+			continue
+		}
+		if flds[0] == "arch" && len(flds) == 2 {
+			t, ok := timingtables[flds[1]]
+			if !ok {
+				t = &TimingTable{name: flds[1], cache: make(map[string]Timing)}
+				timingtables[flds[1]] = t
+			}
+			table = t
+			continue
+		}
+		if table == nil || len(flds) < 3 {
+			return fmt.Errorf("timings line %d: expected 'arch <name>' or '<mnemonic> <latency> <throughput> [ports]'", linenr)
+		}
+		lat, err1 := strconv.ParseFloat(flds[1], 64)
+		thr, err2 := strconv.ParseFloat(flds[2], 64)
+		if err1 != nil || err2 != nil {
+			return fmt.Errorf("timings line %d: bad number", linenr)
+		}
+		t := Timing{pattern: flds[0], latency: lat, throughput: thr}
+		if len(flds) > 3 {
+			t.ports = flds[3]
+		}
+		added[table.name] = append(added[table.name], t)
+	}
+	for name, entries := range added {
+		t := timingtables[name]
+		t.entries = append(entries, t.entries...)
+		t.cache = make(map[string]Timing)
+	}
+	return scanner.Err()
+}
+
+// inittimings reads the built in tables and the user file, if given
+func inittimings(filename string) error {
+	if len(timingtables) > 0 {
+		return nil
+	}
+	if err := readtimings(strings.NewReader(builtintimings)); err != nil {
+		panic(err)
+	}
+	if filename != "" {
+		f, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return readtimings(f)
+	}
+	return nil
+}
+
+// lookup returns the timing for a mnemonic, 1 cycle on any port if not found
+func (tt *TimingTable) lookup(mnemonic string) Timing {
+	if t, ok := tt.cache[mnemonic]; ok {
+		return t
+	}
+	t := Timing{pattern: "?", latency: 1, throughput: 1}
+	for _, e := range tt.entries {
+		if ok, _ := path.Match(e.pattern, mnemonic); ok {
+			t = e
+			break
+		}
+	}
+	tt.cache[mnemonic] = t
+	return t
+}
+
+// instance of an instruction in a simulated iteration
+type siminstance struct {
+	line    int
+	latency float64
+	finish  float64
+	pred    int // index of instance determining the start, -1 if none
+	iter    int
+}
+
+// Estimate is the result of the timing model
+type Estimate struct {
+	arch            string
+	loop            bool
+	cycles          float64 // estimated cycles per iteration
+	latencybound    float64
+	throughputbound float64
+	bottleneck      string             // port or unit with the highest pressure
+	pressure        map[string]float64 // cycles per iteration per port or unit
+	chain           []int              // lines of critical dependency chain, first to last
+	latencies       []float64          // latencies of the instructions in chain
+	carried         bool               // chain starts with a value of the previous iteration
+}
+
+// estimate runs the timing model over the instruction lines in region
+func (a *AssemblerFile) estimate(region []int, loop bool, table *TimingTable) Estimate {
+	est := Estimate{arch: table.name, loop: loop, pressure: make(map[string]float64)}
+	iterations := 1
+	if loop {
+		iterations = simiterations
+	}
+
+	instrs := make([]Instruction, 0, len(region))
+	lines := make([]int, 0, len(region))
+	for _, l := range region {
+		if ins, ok := parseInstruction(a.filebuffer.GetLine(l)); ok {
+			instrs = append(instrs, ins)
+			lines = append(lines, l)
+		}
+	}
+	if len(instrs) == 0 {
+		return est
+	}
+
+	ready := make(map[string]float64) // register -> time value is ready
+	writer := make(map[string]int)    // register -> instance writing it
+	unitfree := make(map[byte]float64)
+	instances := make([]siminstance, 0, len(instrs)*iterations)
+	lastissue := 0.0
+	iterend := make([]float64, iterations)
+	uops := 0.0
+
+	for it := 0; it < iterations; it++ {
+		for i, ins := range instrs {
+			l := lines[i]
+			t := table.lookup(ins.mnemonic)
+			latency := t.latency
+
+			// inputs
+			used := usedregisters(ins, a.ve)
+			if access, ok := a.stackaccess[l]; ok && access.load {
+				used = append(used, fmt.Sprintf("slot%d", access.slot))
+			}
+			start := 0.0
+			pred := -1
+			for _, r := range used {
+				if ready[r] > start {
+					start = ready[r]
+					pred = writer[r]
+				}
+			}
+
+			if !a.ve {
+				// memory operands add the load latency and use the load ports
+				load, store := memoryaccess(ins)
+				if load {
+					lt := table.lookup("load")
+					latency += lt.latency
+					if it == 0 {
+						est.addpressure(lt)
+					}
+				}
+				if store && it == 0 {
+					est.addpressure(table.lookup("store"))
+				}
+				if it == 0 {
+					est.addpressure(t)
+					uops++
+				}
+			} else {
+				// in order issue, wait for a free unit
+				occupancy := t.throughput
+				if isvector(ins) {
+					if v, ok := a.vlof(l); ok && (v.state == vlConst || v.state == vlSymbolic && v.value > 0) {
+						occupancy = t.throughput * float64(v.value) / vemaxvl
+					}
+				}
+				start = maxf(start, lastissue)
+				unit := byte(0)
+				if t.ports != "" {
+					unit = t.ports[0]
+					for u := 0; u < len(t.ports); u++ {
+						if unitfree[t.ports[u]] < unitfree[unit] {
+							unit = t.ports[u]
+						}
+					}
+					start = maxf(start, unitfree[unit])
+					unitfree[unit] = start + occupancy
+				}
+				lastissue = start + 1.0
+				if it == 0 {
+					est.pressure[string(unit)] += occupancy
+				}
+			}
+
+			finish := start + latency
+			instances = append(instances, siminstance{line: l, latency: latency, finish: finish, pred: pred, iter: it})
+			if def := definedregister(ins, a.ve); def != "" {
+				ready[def] = finish
+				writer[def] = len(instances) - 1
+			}
+			if access, ok := a.stackaccess[l]; ok && access.store {
+				r := fmt.Sprintf("slot%d", access.slot)
+				ready[r] = finish
+				writer[r] = len(instances) - 1
+			}
+			iterend[it] = maxf(iterend[it], finish)
+			if a.ve {
+				iterend[it] = maxf(iterend[it], lastissue)
+			}
+		}
+	}
+
+	// bounds
+	if loop {
+		est.latencybound = (iterend[iterations-1] - iterend[0]) / float64(iterations-1)
+	} else {
+		est.latencybound = iterend[0]
+	}
+	for p, v := range est.pressure {
+		if v > est.throughputbound {
+			est.throughputbound = v
+			est.bottleneck = p
+		}
+	}
+	if !a.ve && uops/x86issuewidth > est.throughputbound {
+		est.throughputbound = uops / x86issuewidth
+		est.bottleneck = "issue"
+	}
+	est.cycles = maxf(est.latencybound, est.throughputbound)
+
+	// critical chain, walk back from the instance finishing last in the last iteration
+	last := -1
+	for i := len(instances) - len(instrs); i < len(instances); i++ {
+		if last == -1 || instances[i].finish > instances[last].finish {
+			last = i
+		}
+	}
+	for i := last; i != -1; i = instances[i].pred {
+		if instances[i].iter != iterations-1 {
+			est.carried = true
+			break
+		}
+		est.chain = append([]int{instances[i].line}, est.chain...)
+		est.latencies = append([]float64{instances[i].latency}, est.latencies...)
+	}
+	return est
+}
+
+// addpressure puts an instruction on the least busy of its ports.
+// it keeps the port busy for throughput times the number of ports it could use
+func (e *Estimate) addpressure(t Timing) {
+	if t.ports == "" {
+		return
+	}
+	port := string(t.ports[0])
+	for i := 1; i < len(t.ports); i++ {
+		if e.pressure[string(t.ports[i])] < e.pressure[port] {
+			port = string(t.ports[i])
+		}
+	}
+	e.pressure[port] += t.throughput * float64(len(t.ports))
+}
+
+// timingregion returns the lines of the region for the model and if it is a loop.
+// the region is the marked lines, or the basic block containing line
+func (a *AssemblerFile) timingregion(marked map[int]bool, line int) ([]int, bool) {
+	region := make([]int, 0, 64)
+	if len(marked) > 0 {
+		for l := range marked {
+			region = append(region, l)
+		}
+		sort.Ints(region)
+	} else {
+		fi := a.functionof(line)
+		if fi == -1 {
+			return region, false
+		}
+		blocks := a.buildblocks(fi)
+		bi := blockof(blocks, line)
+		if bi == -1 {
+			return region, false
+		}
+		for l := blocks[bi].start; l <= blocks[bi].end; l++ {
+			region = append(region, l)
+		}
+	}
+
+	// a region is a loop if it ends with a branch back to its start
+	first, last := region[0], region[len(region)-1]
+	for l := last; l >= first; l-- {
+		if ins, ok := parseInstruction(a.filebuffer.GetLine(l)); ok {
+			if target := a.branchtarget(ins); target != "" {
+				head := a.labels[target]
+				return region, head <= first && a.functionof(head) == a.functionof(first)
+			}
+			break
+		}
+	}
+	return region, false
+}
+
+// timingtable returns the table for the architecture of the file
+func (a *AssemblerFile) timingtable(uarch string) (*TimingTable, error) {
+	name := uarch
+	if a.ve {
+		name = "ve"
+	} else if name == "" {
+		name = defaultuarch
+	}
+	table, ok := timingtables[name]
+	if !ok {
+		return nil, fmt.Errorf("no timing table for %s", name)
+	}
+	return table, nil
+}
+
+// report returns list entries describing the estimate
+func (e Estimate) report(a *AssemblerFile) []ListEntry {
+	entries := make([]ListEntry, 0, 16)
+	kind := "block"
+	if e.loop {
+		kind = fmt.Sprintf("loop, %d iterations simulated", simiterations)
+	}
+	entries = append(entries, ListEntry{fmt.Sprintf("estimated cycles per iteration: %.2f (%s, %s)", e.cycles, kind, e.arch), 0})
+	bound := "latency bound"
+	if e.throughputbound > e.latencybound {
+		bound = "throughput bound"
+	}
+	entries = append(entries, ListEntry{fmt.Sprintf("latency bound: %.2f  throughput bound: %.2f (%s)  -> %s", e.latencybound, e.throughputbound, e.bottleneck, bound), 0})
+	ports := make([]string, 0, len(e.pressure))
+	for p := range e.pressure {
+		ports = append(ports, p)
+	}
+	sort.Strings(ports)
+	pressure := "pressure per iteration:"
+	for _, p := range ports {
+		pressure += fmt.Sprintf("  %s=%.2f", p, e.pressure[p])
+	}
+	entries = append(entries, ListEntry{pressure, 0})
+	entries = append(entries, ListEntry{"", 0})
+	chain := "critical dependency chain:"
+	if e.carried {
+		chain += " (continues from previous iteration)"
+	}
+	entries = append(entries, ListEntry{chain, 0})
+	for i, l := range e.chain {
+		entries = append(entries, ListEntry{fmt.Sprintf("%8d  lat %4.0f  %s", l, e.latencies[i], strings.TrimSpace(demangleline(a.filebuffer.GetLine(l)))), l})
+	}
+	return entries
+}
+
+// max of 2 float64
+func maxf(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
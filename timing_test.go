@@ -0,0 +1,111 @@
+package main
+
+/*
+	tests of the timing tables and the timing model
+*/
+
+import (
+	"strings"
+	"testing"
+)
+
+const testtimings = `
+arch test
+addsd   4  0.5  01   # comment
+add*    1  0.25 0156
+load    5  0.5  23
+store   1  1    4
+`
+
+// testtable returns the timing table test, with the built in tables read before
+func testtable(t *testing.T) *TimingTable {
+	t.Helper()
+	if err := inittimings(""); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := timingtables["test"]; !ok {
+		if err := readtimings(strings.NewReader(testtimings)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return timingtables["test"]
+}
+
+func TestReadTimings(t *testing.T) {
+	table := testtable(t)
+	tests := []struct {
+		mnemonic   string
+		latency    float64
+		throughput float64
+		ports      string
+	}{
+		{"addsd", 4, 0.5, "01"}, // first matching entry wins
+		{"addq", 1, 0.25, "0156"},
+		{"imulq", 1, 1, ""}, // not in the table
+	}
+	for _, test := range tests {
+		if e := table.lookup(test.mnemonic); e.latency != test.latency || e.throughput != test.throughput || e.ports != test.ports {
+			t.Errorf("lookup(%q) = %v, want %v %v %q", test.mnemonic, e, test.latency, test.throughput, test.ports)
+		}
+	}
+	if e := timingtables["ve"].lookup("vfmad.d"); e.pattern == "?" {
+		t.Errorf("no built in timing for vfmad.d")
+	}
+
+	// a table without entries
+	if err := readtimings(strings.NewReader("arch empty\n")); err != nil {
+		t.Fatal(err)
+	}
+	if e := timingtables["empty"].lookup("addsd"); e.pattern != "?" {
+		t.Errorf("lookup in empty table = %v", e)
+	}
+
+	for _, bad := range []string{"addsd 4 1\n", "arch test\naddsd 4\n", "arch test\naddsd four 1\n"} {
+		if err := readtimings(strings.NewReader(bad)); err == nil {
+			t.Errorf("no error for %q", bad)
+		}
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	table := testtable(t)
+	a := testfile(t, `	.text
+	.globl	f
+	.type	f, @function
+f:
+	addsd	(%rax), %xmm0
+	addsd	%xmm1, %xmm0
+	addsd	%xmm2, %xmm0
+.L2:
+	addsd	%xmm1, %xmm3
+	subq	$1, %rcx
+	jne	.L2
+	ret
+	.size	f, .-f
+`)
+	first := linewith(t, a, "(%rax), %xmm0")
+
+	// block: the chain through %xmm0 with the load in front
+	region, loop := a.timingregion(nil, first)
+	if loop || len(region) == 0 || region[0] > first {
+		t.Fatalf("region %v, loop %v", region, loop)
+	}
+	est := a.estimate(region, loop, table)
+	if est.latencybound != 5+4+4+4 || est.throughputbound != 2 || est.cycles != 17 || est.bottleneck != "0" {
+		t.Errorf("latency bound %v, throughput bound %v on %s, cycles %v, want 17, 2 on 0, 17",
+			est.latencybound, est.throughputbound, est.bottleneck, est.cycles)
+	}
+	if len(est.chain) != 3 || est.chain[0] != first || est.latencies[0] != 9 {
+		t.Errorf("chain %v with latencies %v", est.chain, est.latencies)
+	}
+
+	// loop: the sum in %xmm3 is carried from iteration to iteration
+	region, loop = a.timingregion(nil, linewith(t, a, "%xmm1, %xmm3"))
+	if !loop {
+		t.Fatalf("region %v is no loop", region)
+	}
+	est = a.estimate(region, loop, table)
+	if est.latencybound != 4 || est.cycles != 4 || !est.carried {
+		t.Errorf("latency bound %v, cycles %v, carried %v, want 4, 4, true", est.latencybound, est.cycles, est.carried)
+	}
+}
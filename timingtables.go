@@ -0,0 +1,166 @@
+package main
+
+/*
+	built in latency and throughput tables
+
+	format, one instruction per line:
+
+		arch <name>
+		<mnemonic> <latency> <reciprocal throughput> [ports]
+
+	mnemonic can contain * as wildcard, the first matching line wins,
+	so specific entries go before general ones. ports is a string
+	with one character per port (x86) or unit (VE) the instruction
+	can be issued to. for x86, the pseudo instructions load and store
+	give the cost of a memory operand. for VE vector instructions,
+	throughput is given for vector length 256 and scaled down for
+	shorter vectors.
+	a file given with --timings has the same format, its lines are
+	searched before the built in ones.
+
+	numbers are rounded values from public measurements and manuals,
+	they are meant for relative comparisons, not exact cycle counts.
+
+	the table is a constant instead of a data file so the binary works
+	without any files installed next to it, go 1.15 has no go:embed.
+*/
+
+const builtintimings = `
+arch ve
+# units: A scalar alu, M scalar memory, B branch,
+#        F G fma pipes, X vector alu, D vector divide, L vector load, S vector store
+bsic     2   1   B
+b*       1   1   B
+ld*      30  1   M
+dld*     30  1   M
+st*      1   1   M
+lea*     1   1   A
+mul*     6   1   A
+div*     30  30  A
+fdiv*    30  30  A
+fsqrt*   30  30  A
+f*       6   1   A
+lvl      1   1   A
+vld*     100 8   L
+vst*     1   8   S
+vgt*     150 64  L
+vsc*     1   64  S
+vfdiv*   60  64  D
+vfsqrt*  60  64  D
+vdiv*    60  64  D
+vfsum*   20  8   X
+vfmax*   20  8   X
+vfmin*   20  8   X
+vsum*    20  8   X
+vfmad*   9   8   FG
+vfmsb*   9   8   FG
+vfnmad*  9   8   FG
+vfnmsb*  9   8   FG
+vfmul*   9   8   FG
+vfadd*   9   8   FG
+vfsub*   9   8   FG
+pvf*     9   8   FG
+vbrd*    4   8   X
+vcp*     10  8   X
+vex*     10  8   X
+vf*      9   8   FGX
+v*       4   8   X
+pv*      4   8   X
+*        1   1   A
+
+arch skylake
+# ports 0 1 5 6 alu, 2 3 load, 4 store data, 7 store address
+load     5   0.5  23
+store    1   1    4
+div[bwlq] 26 6    0
+idiv[bwlq] 26 6   0
+imul*    3   1    1
+mul[bwlq] 3  1    1
+j*       1   0.5  06
+call*    2   1    6
+ret*     2   1    6
+sh[lr]*  1   0.5  06
+sa[lr]*  1   0.5  06
+ro[lr]*  1   0.5  06
+push*    1   1    4
+pop*     1   0.5  23
+vdivsd   14  4    0
+vdivss   11  3    0
+vdivpd   14  8    0
+vdivps   11  5    0
+divsd    14  4    0
+divss    11  3    0
+divpd    14  4    0
+divps    11  3    0
+*sqrt*   18  6    0
+vfmadd*  4   0.5  01
+vfmsub*  4   0.5  01
+vfnmadd* 4   0.5  01
+vfnmsub* 4   0.5  01
+*mulsd   4   0.5  01
+*mulss   4   0.5  01
+*mulpd   4   0.5  01
+*mulps   4   0.5  01
+*addsd   4   0.5  01
+*addss   4   0.5  01
+*addpd   4   0.5  01
+*addps   4   0.5  01
+*subsd   4   0.5  01
+*subss   4   0.5  01
+*subpd   4   0.5  01
+*subps   4   0.5  01
+*cvt*    4   1    01
+*perm*   3   1    5
+*shuf*   1   1    5
+*unpck*  1   1    5
+*broadcast* 3 1   5
+*gather* 20  5    0
+*scatter* 11 5    0
+*mov*    1   0.333 015
+v*       1   0.5  015
+*        1   0.25 0156
+
+arch zen2
+# ports 0 1 2 3 alu, L M N address generation, A B C D floating point
+load     4   0.333 LMN
+store    1   1    LMN
+div[bwlq] 40 20   2
+idiv[bwlq] 40 20  2
+imul*    3   1    1
+mul[bwlq] 3  1    1
+j*       1   0.5  03
+call*    2   1    3
+ret*     2   1    3
+push*    1   1    LMN
+pop*     1   0.5  LMN
+*divsd   13  4.5  D
+*divss   10  3.5  D
+*divpd   13  5    D
+*divps   10  3.5  D
+*sqrt*   20  8    D
+vfmadd*  5   0.5  AB
+vfmsub*  5   0.5  AB
+vfnmadd* 5   0.5  AB
+vfnmsub* 5   0.5  AB
+*mulsd   3   0.5  AB
+*mulss   3   0.5  AB
+*mulpd   3   0.5  AB
+*mulps   3   0.5  AB
+*addsd   3   0.5  CD
+*addss   3   0.5  CD
+*addpd   3   0.5  CD
+*addps   3   0.5  CD
+*subsd   3   0.5  CD
+*subss   3   0.5  CD
+*subpd   3   0.5  CD
+*subps   3   0.5  CD
+*cvt*    4   1    CD
+*perm*   3   1    BC
+*shuf*   1   0.5  BC
+*unpck*  1   0.5  BC
+*broadcast* 1 0.5 BC
+*gather* 20  9    AB
+*mov*    1   0.25 ABCD
+v*       1   0.25 ABCD
+*        1   0.25 0123
+`
@@ -37,6 +37,7 @@ import (
 	"os"
 	"os/signal"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
@@ -56,11 +57,26 @@ type TuiT struct {
 
 	focus int // 0=top 1=middle
 
-	toptopline int          // file coordinate, 1 in beginning, line number of first line on screen
+	toptopline int          // row coordinate, 1 in beginning, row number of first line on screen
 	toplines   int          // number of lines of top panel (size, has to be updated in resize)
 	topcursor  int          // screen coordinate of cursor line (0-(toplines-2))
 	topmarked  map[int]bool // marked lines in file coordinates (so we do not have to care of scrolling)
 	topmodel   PanelModel   // the data model for top view
+	topgutter  int          // gutter in front of lines: 0 = none, 1 = vector length
+	topwidth   int          // width of top panel, half of screen if other is open
+	toprows    []viewrow    // rows of top panel, nil if rows are file lines (see view.go)
+
+	interleaved bool            // source lines are shown as headers in top panel
+	filters     [nrfilters]bool // filters switched on for top panel (see filter.go)
+	folds       map[int]Fold    // folds of top panel by first line (see fold.go)
+
+	banding        bool           // lines are drawn in the color of their source line (see band.go)
+	bandsource     map[int]int16  // caching: band colors of source lines with generated code
+	bandfile       *AssemblerFile // caching: file and source of bandsource
+	bandsourcename string
+
+	other        *topview // second assembler panel for comparison, nil if none
+	otherfocused bool     // other is swapped into top and has the focus
 
 	middletopline int          // file coordinate, 1 in beginning, line number of first line on screen
 	middlelines   int          // number of lines of middle panel (size, has to be updated in resize)
@@ -72,7 +88,6 @@ type TuiT struct {
 
 	opsve        *Opstable
 	opsx86       *Opstable
-	explainre    *regexp.Regexp
 	searchinput  bool
 	searchstring string
 	searchdir    int
@@ -91,7 +106,6 @@ func NewTui() *TuiT {
 
 	newtui.opsve = NewOpstableVE()
 	newtui.opsx86 = NewOpstableX86()
-	newtui.explainre = regexp.MustCompile(`^\s+(.+?)[\[\s].+$`)
 
 	newtui.scr, err = gc.Init()
 	if err != nil {
@@ -110,13 +124,16 @@ func NewTui() *TuiT {
 	}
 
 	// FIXME make those constants named
-	gc.InitPair(1, gc.C_WHITE, gc.C_BLACK)   // 1 = White on Black, normal text
-	gc.InitPair(2, gc.C_BLUE, gc.C_YELLOW)   // 2 = Blue on yellow, selection
-	gc.InitPair(3, gc.C_BLUE, gc.C_BLACK)    // 3 = Blue on black, comments
-	gc.InitPair(4, gc.C_RED, gc.C_BLACK)     // 4 = Red on black, labels
-	gc.InitPair(5, gc.C_CYAN, gc.C_BLACK)    // 5 = Green on black, directives
-	gc.InitPair(6, gc.C_MAGENTA, gc.C_BLACK) // 6 = Magenta on black, local labels
-	gc.InitPair(7, gc.C_RED, gc.C_WHITE)     // 7 = Red on white, active tab
+	gc.InitPair(1, gc.C_WHITE, gc.C_BLACK)           // 1 = White on Black, normal text
+	gc.InitPair(2, gc.C_BLUE, gc.C_YELLOW)           // 2 = Blue on yellow, selection
+	gc.InitPair(3, gc.C_BLUE, gc.C_BLACK)            // 3 = Blue on black, comments
+	gc.InitPair(4, gc.C_RED, gc.C_BLACK)             // 4 = Red on black, labels
+	gc.InitPair(5, gc.C_CYAN, gc.C_BLACK)            // 5 = Green on black, directives
+	gc.InitPair(6, gc.C_MAGENTA, gc.C_BLACK)         // 6 = Magenta on black, local labels
+	gc.InitPair(7, gc.C_RED, gc.C_WHITE)             // 7 = Red on white, active tab
+	gc.InitPair(8, gc.C_YELLOW, gc.C_BLACK)          // 8 = Yellow on black, spills and reloads in loops
+	initbands()                                      // 9-14 = source line bands
+	gc.InitPair(hlDirective, gc.C_GREEN, gc.C_BLACK) // 15 = Green on black, compiler directives in source
 
 	newtui.maxy, newtui.maxx = newtui.scr.MaxYX()
 
@@ -131,6 +148,7 @@ func NewTui() *TuiT {
 	newtui.middlelines = 0 // size of middle window
 
 	newtui.toplines = newtui.maxy - 1 - newtui.bottomlines - newtui.middlelines - 1
+	newtui.topwidth = newtui.maxx
 
 	newtui.toptopline = 1
 	newtui.topcursor = 0 // cursor is in screen coordinates
@@ -211,8 +229,7 @@ func (t *TuiT) Resize() {
 	t.maxy, t.maxx = t.scr.MaxYX()
 	t.toplines = t.maxy - 1 - t.bottomlines - t.middlelines - 1
 
-	t.top.Resize(t.toplines, t.maxx)
-	t.topbar.MoveWindow(t.toplines, 0)
+	t.layouttop()
 
 	t.middle.Resize(t.middlelines, t.maxx)
 	t.middle.MoveWindow(t.toplines+1, 0)
@@ -221,6 +238,7 @@ func (t *TuiT) Resize() {
 
 	t.bottom.Resize(t.bottomlines, t.maxx)
 	t.Refreshtopall()
+	t.drawother()
 }
 
 // Refresh everything
@@ -252,21 +270,72 @@ func (t *TuiT) Refreshmiddleall() {
 	gc.Update()
 }
 
+// guttertop returns the gutter text for a line in file coordinates, all lines have same length.
+// lines with lint findings are marked with ! if there are any findings
+func (t *TuiT) guttertop(line int) string {
+	gutter := ""
+	if len(assemblerfile.findings) > 0 {
+		if _, ok := assemblerfile.findingsat[line]; ok {
+			gutter = "!"
+		} else {
+			gutter = " "
+		}
+	}
+	switch t.topgutter {
+	case 1:
+		if v, ok := assemblerfile.vlof(line); ok {
+			return gutter + fmt.Sprintf("%-7.7s ", v.short())
+		}
+		return gutter + "        "
+	}
+	return gutter
+}
+
 // drawlinetop, y in screen coordinates
 func (t *TuiT) drawlinetop(y int) {
-	for x := 0; x < mini(t.maxx, t.topmodel.GetLineLen(y+t.toptopline)); x++ {
-		r, color, attr := t.topmodel.GetCell(x, y+t.toptopline)
+	row := t.viewrowtop(y + t.toptopline)
+	line, header := row.line, row.header
+	gutter := t.guttertop(line)
+	if header || row.end > 0 {
+		gutter = strings.Repeat(" ", len(gutter))
+	}
+	if gutter != "" {
+		t.top.ColorOn(3)
+		t.top.MovePrint(y, 0, gutter)
+		if gutter[0] == '!' {
+			t.top.ColorOn(4)
+			t.top.MovePrint(y, 0, "!")
+		}
+	}
+	offset := len(gutter)
+	if header {
+		t.drawheadertop(y, line, offset)
+		return
+	}
+	if row.end > 0 {
+		t.drawfoldtop(y, row, offset)
+		return
+	}
+	linecolor := int16(0)
+	if t.banding {
+		linecolor = bandtop(line)
+	}
+	_, marked := t.topmarked[line]
+	if marked {
+		linecolor = 2
+	}
+	for x := 0; x < mini(t.topwidth-offset, t.topmodel.GetLineLen(line)); x++ {
+		r, color, attr := t.topmodel.GetCell(x, line)
 		t.top.AttrOn(attr)
 		t.top.ColorOn(color)
-		_, ok := t.topmarked[y+t.toptopline]
-		if color == 1 && ok {
-			t.top.ColorOn(2)
+		if (color == 1 || color == 8) && linecolor != 0 {
+			t.top.ColorOn(linecolor)
 		}
 
 		if y == t.topcursor {
 			t.top.AttrOn(gc.A_BOLD)
 		}
-		t.top.MovePrint(y, x, string(r))
+		t.top.MovePrint(y, x+offset, string(r))
 		t.top.AttrOff(attr)
 		t.top.AttrOff(gc.A_BOLD)
 		t.top.AttrOff(gc.A_REVERSE) // selection
@@ -276,13 +345,20 @@ func (t *TuiT) drawlinetop(y int) {
 
 // drawlinemiddle, y in screen coordinates
 func (t *TuiT) drawlinemiddle(y int) {
+	linecolor := int16(0)
+	if t.banding {
+		linecolor = t.bandmiddle(y + t.middletopline)
+	}
+	_, marked := t.middlemarked[y+t.middletopline]
+	if marked {
+		linecolor = 2
+	}
 	for x := 0; x < mini(t.maxx, t.middlemodel.GetLineLen(y+t.middletopline)); x++ {
 		r, color, attr := t.middlemodel.GetCell(x, y+t.middletopline)
 		t.middle.AttrOn(attr)
 		t.middle.ColorOn(color)
-		_, ok := t.middlemarked[y+t.middletopline]
-		if color == 1 && ok {
-			t.middle.ColorOn(2)
+		if linecolor != 0 {
+			t.middle.ColorOn(linecolor)
 		}
 
 		if y == t.middlecursor {
@@ -305,8 +381,12 @@ func (t *TuiT) refreshtopbar() {
 		t.topbar.AttrOn(gc.A_REVERSE)
 		t.topbar.ColorOn(1)
 	}
-	t.topbar.Print(fmt.Sprintf("%-*s", t.maxx, " "+t.topmodel.GetFilename()+" in global symbol: "+t.topmodel.GetSymbol(t.toptopline+t.topcursor)))
-	t.topbar.MovePrint(0, t.maxx-20, fmt.Sprintf("%d/%d", t.toptopline+t.topcursor, t.topmodel.GetNrLines()))
+	status := " " + t.topmodel.GetFilename()
+	if kind := assemblerfile.functionlabel(t.cursortop()); kind != "" {
+		status += " in " + kind + ": " + symbolname(t.topmodel.GetSymbol(t.cursortop()))
+	}
+	t.topbar.Print(fmt.Sprintf("%-*.*s", t.topwidth, t.topwidth, status))
+	t.topbar.MovePrint(0, t.topwidth-20, fmt.Sprintf("%d/%d", t.cursortop(), t.topmodel.GetNrLines()))
 	t.topbar.AttrOff(gc.A_REVERSE)
 	t.topbar.AttrOff(gc.A_BOLD)
 
@@ -322,13 +402,15 @@ func (t *TuiT) refreshtopbar() {
 				min = i
 			}
 		}
-		if min < t.toptopline {
+		first, _ := t.linetop(t.toptopline)
+		last, _ := t.linetop(t.toptopline + t.toplines)
+		if min < first {
 			t.topbar.ColorOn(2)
-			t.topbar.MovePrint(0, t.maxx-24, "<")
+			t.topbar.MovePrint(0, t.topwidth-24, "<")
 		}
-		if max > t.toptopline+t.toplines {
+		if max > last {
 			t.topbar.ColorOn(2)
-			t.topbar.MovePrint(0, t.maxx-23, ">")
+			t.topbar.MovePrint(0, t.topwidth-23, ">")
 		}
 	}
 
@@ -376,13 +458,13 @@ func (t *TuiT) refreshmiddle() {
 // move cursor DOWN top window
 func (t *TuiT) sdowntop() {
 	updated := false
-	if t.topcursor < t.toplines-2 && t.topcursor < t.topmodel.GetNrLines()-1 {
+	if t.topcursor < t.toplines-2 && t.topcursor < t.nrrowstop()-1 {
 		t.topcursor++
 		t.drawlinetop(t.topcursor - 1)
 		t.drawlinetop(t.topcursor)
 		updated = true
 	} else {
-		if t.toptopline+t.toplines < t.topmodel.GetNrLines()+2 {
+		if t.toptopline+t.toplines < t.nrrowstop()+2 {
 			t.top.Scroll(1)
 			t.toptopline++
 			t.drawlinetop(t.toplines - 1) // new line
@@ -473,11 +555,11 @@ func (t *TuiT) supmiddle() {
 
 // page down top window
 func (t *TuiT) pagedowntop() {
-	if t.toptopline+t.toplines > t.topmodel.GetNrLines() {
+	if t.toptopline+t.toplines > t.nrrowstop() {
 		// this means all file is on screen, lets move cursor to end of files
 		t.jumpendtop()
 	} else {
-		t.toptopline = mini(t.topmodel.GetNrLines()-t.toplines+1, t.toptopline+t.toplines)
+		t.toptopline = mini(t.nrrowstop()-t.toplines+1, t.toptopline+t.toplines)
 		t.Refreshtopall()
 	}
 }
@@ -519,8 +601,8 @@ func (t *TuiT) jumphometop() {
 
 func (t *TuiT) jumpendtop() {
 	t.top.Erase()
-	t.toptopline = maxi(1, t.topmodel.GetNrLines()-t.toplines+2)
-	t.topcursor = mini(t.topmodel.GetNrLines()-t.toptopline, t.toplines)
+	t.toptopline = maxi(1, t.nrrowstop()-t.toplines+2)
+	t.topcursor = mini(t.nrrowstop()-t.toptopline, t.toplines)
 	t.Refreshtopall()
 }
 
@@ -537,10 +619,11 @@ func (t *TuiT) jumpendmiddle() {
 	t.Refreshmiddleall()
 }
 
+// showlinetop shows an assembler line as first line of top, or further down near the end of the file
 func (t *TuiT) showlinetop(line int) {
 	if line <= t.topmodel.GetNrLines() {
-		t.toptopline = mini(line, t.topmodel.GetNrLines()-t.toplines+1)
-		t.topcursor = 0
+		t.toptopline = maxi(1, mini(t.rowtop(line), t.nrrowstop()-t.toplines+1))
+		t.topcursor = t.rowtop(line) - t.toptopline
 		t.Refreshtopall()
 	}
 }
@@ -555,112 +638,67 @@ func (t *TuiT) showlinemiddle(line int) {
 
 // explain an assembly instruction for VE
 func (t *TuiT) explainVE() {
-	line := t.topmodel.GetLine(t.toptopline + t.topcursor)
-	// find main explanation
-	m := t.explainre.FindStringSubmatch(line)
-	if m == nil {
-		// for lines without spaces at end
-		r := regexp.MustCompile(`^\s+(.+?)$`)
-		m = r.FindStringSubmatch(line)
-		if m == nil {
-			return // bail out for lines not matching
-		}
+	lines := explanationVE(t.opsve, t.topmodel.GetLine(t.cursortop()))
+	if lines == nil {
+		return // bail out for lines not matching
 	}
 	t.bottom.Erase()
-	//t.bottom.Println("try <", m[1], "> ")
-	e := t.opsve.getops(m[1])
-	if e != "" {
-		t.bottom.Println(e)
-	} else {
-		// FIXME what about . in first position?
-		tokens := strings.Split(m[1], ".")
-	outer:
-		for i := len(tokens); i >= 1; i-- {
-			o := tokens[0]
-			for j := 1; j < i; j++ {
-				o = o + "." + tokens[j]
-			}
-			if o != "" {
-				// t.bottom.Println("try <", o, "> ")
-				e := t.opsve.getops(o)
-				if e != "" {
-					t.bottom.Println(e)
-					break outer
-				}
-			}
+	for i, l := range lines {
+		if i > 0 {
+			t.bottom.Println()
 		}
+		t.bottom.Print(l)
 	}
-	// explain suffixes
-	tokens := strings.Fields(line)
-	first := true
-	for suffix := range suffixes {
-		if strings.Index(tokens[0]+".", suffix+".") >= 0 {
-			if !first {
-				t.bottom.Print(", ")
-			}
-			t.bottom.Print(suffix, ":", suffixes[suffix])
-			first = false
-		}
-	}
-	// explain registers
-	if !first {
-		t.bottom.Println()
-	}
-	first = true
-	for register := range registers {
-		if strings.Index(line, register) >= 0 {
-			if !first {
-				t.bottom.Print(", ")
-			}
-			t.bottom.Print(register, ":", registers[register])
-			first = false
-		}
-	}
-
 	t.bottom.NoutRefresh()
 	gc.Update()
 }
 
 // explain an assembly instruction for x86
 func (t *TuiT) explainX86() {
-	line := t.topmodel.GetLine(t.toptopline + t.topcursor)
-	// find main explanation
-	m := t.explainre.FindStringSubmatch(line)
-	if m == nil {
-		// for lines without spaces at end
-		r := regexp.MustCompile(`^\s+(.+?)$`)
-		m = r.FindStringSubmatch(line)
-		if m == nil {
-			return // bail out for lines not matching
+	e, ok := explanationX86(t.topmodel.GetLine(t.cursortop()))
+	if !ok {
+		return // bail out for lines not matching
+	}
+	t.bottom.Erase()
+	t.bottom.Print(e)
+	t.bottom.NoutRefresh()
+	gc.Update()
+}
+
+// explaindirective explains a compiler directive in the source panel and checks the following loop
+func (t *TuiT) explaindirective() {
+	source, ok := t.middlemodel.(*SourceModel)
+	if !ok {
+		return
+	}
+	line := t.middletopline + t.middlecursor
+	d, ok := parsedirective(source.GetLine(line))
+	if !ok {
+		return
+	}
+	lines := d.explain()
+	// the statement following the directive, behind further directives and comments
+	code := make([]int, 0, 2)
+	for next := line + 1; next <= source.GetNrLines() && next <= line+20 && len(code) < 2; next++ {
+		if iscodeline(source.lang, source.GetLine(next)) {
+			code = append(code, next)
 		}
 	}
+	if len(code) > 0 {
+		statement := assemblerfile.asmlinesof(source.GetFilename(), code[0])
+		var body []int
+		if len(code) > 1 {
+			body = assemblerfile.asmlinesof(source.GetFilename(), code[1])
+		}
+		lines = append(lines, assemblerfile.directivecheck(d, statement, body)...)
+	}
 	t.bottom.Erase()
-	// t.bottom.Println("try <", m[1], "> ")
-	e, ok := x86ops[m[1]]
-	if ok {
-		t.bottom.Println(m[1], "=", e)
-	} else {
-		if m[1][0] == 'v' { // see if we find it without the v
-			// t.bottom.Println("try <", m[1][1:], "> ")
-			e, ok := x86ops[m[1][1:]]
-			if ok {
-				t.bottom.Println(m[1], "=", e)
-			} else { // see if we find it without first and last character
-				// t.bottom.Println("try <", m[1][1:len(m[1])-1], "> ")
-				e, ok := x86ops[m[1][1:len(m[1])-1]]
-				if ok {
-					t.bottom.Println(m[1], "=", e)
-				}
-			}
-		} else { // see if we find it without last character
-			// t.bottom.Println("try <", m[1][:len(m[1])-1], "> ")
-			e, ok := x86ops[m[1][:len(m[1])-1]]
-			if ok {
-				t.bottom.Println(m[1], "=", e)
-			}
+	for i, l := range lines {
+		if i > 0 {
+			t.bottom.Println()
 		}
+		t.bottom.Print(l)
 	}
-
 	t.bottom.NoutRefresh()
 	gc.Update()
 }
@@ -675,17 +713,32 @@ func (t *TuiT) help() {
 		"<enter>: explain instruction/show in other view, ",
 		"<home>: jump to top of file ",
 		"<end>/<G>: jump to end of file, ",
-		"<n/p>: jump to next/previous search/marks/global symbol ",
+		"<n/p>: jump to next/previous search/marks/function ",
 		"<i>: position info., ",
 		"<c>: clear selection, ",
 		"<space>/<backspace>: select/deselect line, ",
 		"<m> select lines from same sourceline, ",
 		"<v>: view sourcefile, ",
 		"<V> close sourcefile, ",
-		"<TAB>: change focus, ",
+		"<TAB>: change focus (also between two assembler panels), ",
 		"</>/<?>: search forward/backwards, ",
 		"<d>: highlight dependencies, ",
-		"<b>: follow branch",
+		"<b>: follow branch, call or data reference, ",
+		"<s>: jump between spill and reload, ",
+		"<f>: list of functions, ",
+		"<o>: find symbol, ",
+		"<g>: toggle vector length gutter (VE), ",
+		"<l>: loop report, ",
+		"<a>: arithmetic intensity of loops, ",
+		"<t>: timing estimate of block/marked lines, ",
+		"<w>: lint findings, ",
+		"<S>: toggle source interleaved with assembly, ",
+		"<F>: filters hiding debug sections, directives, labels, library code, ",
+		"<z>: fold/unfold loop, function or section, <e>: fold enclosing range, <Z>/<U>: fold/unfold all, ",
+		"<B>: toggle source line color bands, ",
+		"<D>: toggle demangled/raw symbol names, ",
+		"<x>: toggle variable names of registers, ",
+		"<enter> on a directive in source: explain and check it",
 	}
 
 	for _, m := range msg {
@@ -709,16 +762,154 @@ func (t *TuiT) printwithbreak(text string, w *gc.Window) {
 // print some position info, helps in case of longmangeld c++ names which do not fit into sttaus bar
 func (t *TuiT) posinfo() {
 	t.bottom.Erase()
-	t.bottom.Println("in symbol", t.topmodel.GetSymbol(t.toptopline+t.topcursor))
-	filename, linenr := t.topmodel.GetPosition(t.toptopline + t.topcursor)
+	if fi := assemblerfile.functionof(t.cursortop()); fi >= 0 {
+		f := assemblerfile.functions[fi]
+		t.bottom.Println("in", assemblerfile.functionlabel(t.cursortop()), symbolname(f.name))
+		t.bottom.Println("lines", f.start, "to", f.end, "with", f.instructions, "instructions, size", f.size)
+	} else {
+		t.bottom.Println("outside of functions")
+	}
+	filename, linenr := t.topmodel.GetPosition(t.cursortop())
 	t.bottom.Println("produced for line", linenr, "in", filename)
+	if vars := assemblerfile.describevariables(t.cursortop()); vars != "" {
+		t.bottom.Println("variables in registers:", vars)
+	}
 	t.bottom.NoutRefresh()
 	gc.Update()
 }
 
+// explain stack slot access of current line, appends to bottom window
+func (t *TuiT) explainstack() {
+	desc := assemblerfile.describestack(t.cursortop())
+	if desc != "" {
+		t.bottom.Println()
+		t.bottom.Print(desc)
+		t.bottom.NoutRefresh()
+		gc.Update()
+	}
+}
+
+// show decoded values of constants the current line refers to, appends to bottom window
+func (t *TuiT) explainconstants() {
+	for _, desc := range assemblerfile.describeconstants(t.cursortop()) {
+		t.bottom.Println()
+		t.bottom.Print("constant " + desc)
+		t.bottom.NoutRefresh()
+		gc.Update()
+	}
+}
+
+// explain vector length used by vector instruction of current line, appends to bottom window
+func (t *TuiT) explainvl() {
+	if v, ok := assemblerfile.vlof(t.cursortop()); ok {
+		t.bottom.Println()
+		if v.def > 0 {
+			t.bottom.Print(v.String(), " (lvl in line ", v.def, ")")
+		} else {
+			t.bottom.Print(v.String())
+		}
+		t.bottom.NoutRefresh()
+		gc.Update()
+	}
+}
+
+// explain lint findings of current line, appends to bottom window
+func (t *TuiT) explainlint() {
+	for _, i := range assemblerfile.findingsat[t.cursortop()] {
+		t.bottom.Println()
+		t.bottom.Print("! " + assemblerfile.findings[i].message + " [" + assemblerfile.findings[i].rule + "]")
+		t.bottom.NoutRefresh()
+		gc.Update()
+	}
+}
+
+// toggle between demangled and raw symbol names
+func (t *TuiT) toggledemangling() {
+	rawnames = !rawnames
+	t.top.Erase()
+	t.Refreshtopall()
+	t.drawother()
+}
+
+// toggle annotation of register operands with variable names
+func (t *TuiT) togglevariables() {
+	showvariables = !showvariables
+	t.top.Erase()
+	t.Refreshtopall()
+	t.drawother()
+}
+
+// toggle vector length gutter
+func (t *TuiT) togglevlgutter() {
+	if !assemblerfile.ve {
+		return
+	}
+	if t.topgutter == 1 {
+		t.topgutter = 0
+	} else {
+		t.topgutter = 1
+	}
+	t.top.Erase()
+	t.Refreshtopall()
+}
+
+// jump from a reload to the spill it loads, or from a spill to its next reload
+func (t *TuiT) jumpspill() {
+	line := assemblerfile.spillof(t.cursortop())
+	if line != -1 {
+		t.showlinetop(line)
+	}
+}
+
+// show list of functions with some statistics, jump to selected one
+func (t *TuiT) functionlist() {
+	entries := make([]ListEntry, 0, len(assemblerfile.functions))
+	for _, f := range assemblerfile.functions {
+		entries = append(entries, ListEntry{fmt.Sprintf("%7d %5d %6d %7d  %s", f.instructions, len(f.loops), f.spills, f.reloads, symbolname(f.name)), f.start})
+	}
+	t.jumplist("functions:  instr loops spills reloads  name", entries)
+}
+
+// show vectorization report of all loops, jump to selected loop
+func (t *TuiT) loopreport() {
+	if !assemblerfile.ve {
+		t.jumplist("width  scalar   128   256   512 masked  function/loop", assemblerfile.simdreport())
+		return
+	}
+	t.jumplist("loops: score   vec cont strd vstr  gt  sc mask scalar  loop                 function", assemblerfile.vectorizationreport())
+}
+
+// show flops, memory traffic and arithmetic intensity of all loops, jump to selected loop
+func (t *TuiT) trafficreport() {
+	t.jumplist("flops/it  loaded  stored  flop/B bound    loop                 function", assemblerfile.trafficreport())
+}
+
+// show lint findings, jump to selected one
+func (t *TuiT) lintlist() {
+	t.jumplist("findings:   line  rule             message", assemblerfile.lintreport())
+}
+
+// show estimated cycles and critical dependency chain of marked lines or current block
+func (t *TuiT) timingestimate() {
+	table, err := assemblerfile.timingtable(opts.Uarch)
+	if err != nil {
+		t.bottom.Erase()
+		t.bottom.Print(err.Error())
+		t.bottom.NoutRefresh()
+		gc.Update()
+		return
+	}
+	region, loop := assemblerfile.timingregion(t.topmarked, t.cursortop())
+	if len(region) == 0 {
+		return
+	}
+	est := assemblerfile.estimate(region, loop, table)
+	t.jumplist(fmt.Sprintf("timing estimate for lines %d-%d", region[0], region[len(region)-1]), est.report(assemblerfile))
+}
+
 // mark a single line in top
 func (t *TuiT) marktop() {
-	fileline := t.topcursor + t.toptopline
+	fileline := t.cursortop()
 	t.topmarked[fileline] = true
 	t.refreshtop()
 	gc.Update()
@@ -726,7 +917,7 @@ func (t *TuiT) marktop() {
 
 // mark all lines which result from same source line as marked line
 func (t *TuiT) markalltop() {
-	fileline := t.topcursor + t.toptopline
+	fileline := t.cursortop()
 	filename, line := t.topmodel.GetPosition(fileline)
 	var fileid int
 	for id, name := range assemblerfile.filenametable {
@@ -756,37 +947,12 @@ func (t *TuiT) markalltop() {
 }
 
 func (t *TuiT) showass() {
-	filename := t.middlemodel.GetFilename()
-	line := t.middletopline + t.middlecursor
-	var fileid int
-	for id, name := range assemblerfile.filenametable {
-		if name == filename || strings.Index(filename, "/"+name) > 0 {
-			fileid = id
-			break
-		}
-	}
-	// file 0 and file 1 are same in case of -g, loc contains 1,X
-	if fileid == 0 && len(assemblerfile.filenametable) > 1 && assemblerfile.filenametable[1] == filename {
-		fileid = 1
+	lines := assemblerfile.asmlinesof(t.middlemodel.GetFilename(), t.middletopline+t.middlecursor)
+	for _, l := range lines {
+		t.topmarked[l] = true
 	}
-	firstline := -1
-	// we use loctable to quickly jump to .loc lines and search from there
-	for _, l := range assemblerfile.loctable[loctuple{fileid, line}] {
-		s := l
-		for s < t.topmodel.GetNrLines() {
-			cf, cl := t.topmodel.GetPosition(s)
-			if cf != filename || cl != line {
-				break
-			}
-			t.topmarked[s] = true
-			if firstline == -1 {
-				firstline = s
-			}
-			s++
-		}
-	}
-	if firstline != -1 {
-		t.showlinetop(firstline)
+	if len(lines) > 0 {
+		t.showlinetop(lines[0])
 	}
 	t.refreshtop()
 	gc.Update()
@@ -794,7 +960,7 @@ func (t *TuiT) showass() {
 
 // unmark a line in top
 func (t *TuiT) unmarktop() {
-	fileline := t.topcursor + t.toptopline
+	fileline := t.cursortop()
 	_, ok := t.topmarked[fileline]
 	if ok {
 		delete(t.topmarked, fileline)
@@ -819,11 +985,11 @@ func (t *TuiT) clearmarkmiddle() {
 }
 
 func (t *TuiT) jumpprevioustop() {
-	currline := t.toptopline + t.topcursor
+	currline := t.cursortop()
 	if len(t.topmarked) > 0 {
 		closest := -1
 		for c := range t.topmarked {
-			if c < currline && c > closest {
+			if c < currline && c > closest && t.visibletop(c) {
 				closest = c
 			}
 		}
@@ -835,28 +1001,24 @@ func (t *TuiT) jumpprevioustop() {
 			}
 		}
 	} else {
-		currsymbol := t.topmodel.GetSymbol(currline)
-		l := currline
-		for l > 1 && currsymbol == t.topmodel.GetSymbol(l) {
-			l--
+		// start of the function the cursor is in, or of the one before
+		functions := assemblerfile.functions
+		fi := sort.Search(len(functions), func(i int) bool { return functions[i].start >= currline }) - 1
+		for fi >= 0 && !t.visibletop(functions[fi].start) {
+			fi--
 		}
-		// now search again for start of block
-		currsymbol = t.topmodel.GetSymbol(l)
-		for l > 1 && currsymbol == t.topmodel.GetSymbol(l) {
-			l--
-		}
-		if l != currline {
-			t.showlinetop(l + 1)
+		if fi >= 0 {
+			t.showlinetop(functions[fi].start)
 		}
 	}
 }
 
 func (t *TuiT) jumpnexttop() {
-	currline := t.toptopline + t.topcursor
+	currline := t.cursortop()
 	if len(t.topmarked) > 0 {
 		closest := t.topmodel.GetNrLines() + 1
 		for c := range t.topmarked {
-			if c > currline && c < closest {
+			if c > currline && c < closest && t.visibletop(c) {
 				closest = c
 			}
 		}
@@ -864,22 +1026,23 @@ func (t *TuiT) jumpnexttop() {
 			t.showlinetop(closest)
 		}
 	} else {
-		currsymbol := t.topmodel.GetSymbol(currline)
-		l := currline
-		for l < t.topmodel.GetNrLines()-1 && currsymbol == t.topmodel.GetSymbol(l) {
-			l++
+		functions := assemblerfile.functions
+		fi := sort.Search(len(functions), func(i int) bool { return functions[i].start > currline })
+		for fi < len(functions) && !t.visibletop(functions[fi].start) {
+			fi++
 		}
-		if l != currline {
-			t.showlinetop(l)
+		if fi < len(functions) {
+			t.showlinetop(functions[fi].start)
 		}
 	}
 }
 
+// followbranch jumps to the target of a direct branch, or to the symbol a call or data reference refers to
 func (t *TuiT) followbranch() {
-	oldpos := t.toptopline + t.topcursor
+	oldpos := t.cursortop()
 	line := t.topmodel.GetLine(oldpos)
 	flds := strings.Fields(line)
-	if flds[0][0] == 'b' || flds[0][0] == 'j' {
+	if len(flds) > 1 && (flds[0][0] == 'b' || flds[0][0] == 'j') && !strings.Contains(flds[1], "(") {
 		tokens := strings.Split(flds[1], ",")
 		target := tokens[len(tokens)-1]
 		if target[0] == '.' {
@@ -888,9 +1051,11 @@ func (t *TuiT) followbranch() {
 			t.searchstring = "^" + target
 		}
 		t.search(-1)
-		if t.toptopline+t.topcursor == oldpos {
+		if t.cursortop() == oldpos {
 			t.search(1)
 		}
+	} else if target := assemblerfile.referenceof(oldpos); target > 0 {
+		t.showlinetop(target)
 	}
 }
 
@@ -898,13 +1063,13 @@ func (t *TuiT) followbranch() {
 func (t *TuiT) dependencies() {
 	// search all registers
 	re := regexp.MustCompile(`(%v\d+|%s\d+)|(?:(%[a-z]+)[^,\)])`)
-	matches := re.FindAllString(t.topmodel.GetLine(t.toptopline+t.topcursor), -1)
+	matches := re.FindAllString(t.topmodel.GetLine(t.cursortop()), -1)
 
 	// first register is usually modified, therefor output
 	// vst/vsc and st do not alter first register!
 	// further registers are usually input
 
-	instrwp := strings.Fields(t.topmodel.GetLine(t.toptopline + t.topcursor))[0]
+	instrwp := strings.Fields(t.topmodel.GetLine(t.cursortop()))[0]
 	instr := strings.Split(instrwp, ".")
 
 	output := ""
@@ -946,27 +1111,27 @@ func (t *TuiT) search(dir int) {
 		return
 	}
 	if dir > 0 {
-		if t.toptopline+t.topcursor >= t.topmodel.GetNrLines()-1 {
+		if t.cursortop() >= t.topmodel.GetNrLines()-1 {
 			t.toptopline = 1
 			t.topcursor = 0
 		}
-		for linenr := t.toptopline + t.topcursor + 1; linenr < t.topmodel.GetNrLines(); linenr++ {
+		for linenr := t.cursortop() + 1; linenr < t.topmodel.GetNrLines(); linenr++ {
 			m := re.FindString(t.topmodel.GetLine(linenr))
 			// if strings.Index(t.topmodel.GetLine(linenr), t.searchstring) != -1 {
-			if m != "" {
+			if m != "" && t.visibletop(linenr) {
 				t.showlinetop(linenr)
 				break
 			}
 		}
 	} else {
-		if t.toptopline+t.topcursor <= 2 {
-			t.toptopline = maxi(1, t.topmodel.GetNrLines()-t.toplines+2)
-			t.topcursor = mini(t.topmodel.GetNrLines()-t.toptopline, t.toplines)
+		if t.cursortop() <= 2 {
+			t.toptopline = maxi(1, t.nrrowstop()-t.toplines+2)
+			t.topcursor = mini(t.nrrowstop()-t.toptopline, t.toplines)
 		}
-		for linenr := t.toptopline + t.topcursor - 1; linenr > 1; linenr-- {
+		for linenr := t.cursortop() - 1; linenr > 1; linenr-- {
 			m := re.FindString(t.topmodel.GetLine(linenr))
 			// if strings.Index(t.topmodel.GetLine(linenr), t.searchstring) != -1 {
-			if m != "" {
+			if m != "" && t.visibletop(linenr) {
 				t.showlinetop(linenr)
 				break
 			}
@@ -976,7 +1141,7 @@ func (t *TuiT) search(dir int) {
 
 func (t *TuiT) opensourcefile() bool {
 	var err error
-	filename, _ := t.topmodel.GetPosition(t.toptopline + t.topcursor)
+	filename, _ := t.topmodel.GetPosition(t.cursortop())
 	sourcefile, err = NewSourceFile(filename)
 	if err != nil {
 		t.bottom.Erase()
@@ -1008,7 +1173,7 @@ main:
 			case gc.KEY_BACKSPACE:
 				t.searchstring = t.searchstring[:len(t.searchstring)-1]
 			default:
-				t.searchstring = t.searchstring + string(input)
+				t.searchstring = t.searchstring + string(rune(input))
 			}
 			t.bottom.Erase()
 			t.bottom.Print([3]string{"?", "", "/"}[t.searchdir+1] + t.searchstring)
@@ -1036,7 +1201,7 @@ main:
 			case gc.KEY_BACKSPACE:
 				t.numberstring = t.numberstring[:len(t.numberstring)-1]
 			case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
-				t.numberstring = t.numberstring + string(input)
+				t.numberstring = t.numberstring + string(rune(input))
 			}
 			t.bottom.Erase()
 			t.bottom.Print(t.numberstring)
@@ -1046,7 +1211,7 @@ main:
 			switch input {
 			case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
 				t.numberinput = true
-				t.numberstring = string(input)
+				t.numberstring = string(rune(input))
 				t.bottom.Erase()
 				t.bottom.Print(t.numberstring)
 				t.bottom.NoutRefresh()
@@ -1105,17 +1270,24 @@ main:
 				// FIXME does not work
 				t.bottom.Println("resize!")
 			case gc.KEY_TAB:
-				if t.focus == 0 && t.middlelines > 0 {
+				// with a second assembler panel, focus goes from first to second to source
+				if t.focus == 0 && t.other != nil && !t.otherfocused {
+					t.focusother()
+				} else if t.focus == 0 && t.middlelines > 0 {
 					t.focus = 1
+					t.drawother()
 				} else {
 					t.focus = 0
+					if t.otherfocused {
+						t.focusother()
+					}
 				}
 				t.Refreshtopall()
 				t.Refreshmiddleall()
 			case gc.KEY_RETURN:
 				if t.focus == 0 {
 					if t.middlelines > 0 {
-						filename, line := t.topmodel.GetPosition(t.toptopline + t.topcursor)
+						filename, line := t.topmodel.GetPosition(t.cursortop())
 						if filename == t.middlemodel.GetFilename() {
 							t.middlemarked[line] = true
 							t.showlinemiddle(line)
@@ -1126,11 +1298,15 @@ main:
 							gc.Update()
 						}
 					}
-					if strings.Index(t.topmodel.GetLine(1), ".ident \"n") > -1 {
+					if assemblerfile.ve {
 						t.explainVE()
+						t.explainvl()
 					} else {
 						t.explainX86()
 					}
+					t.explainstack()
+					t.explainconstants()
+					t.explainlint()
 				} else {
 					/*
 						if t.middlelines > 0 {
@@ -1141,6 +1317,7 @@ main:
 						}
 					*/
 					t.showass()
+					t.explaindirective()
 				}
 			case 'h', 'H', gc.KEY_F1:
 				t.help()
@@ -1165,10 +1342,44 @@ main:
 				t.Refresh()
 			case 'b':
 				t.followbranch()
+			case 's':
+				t.jumpspill()
+			case 'f':
+				t.functionlist()
+			case 'g':
+				t.togglevlgutter()
+			case 'l':
+				t.loopreport()
+			case 'a':
+				t.trafficreport()
+			case 't':
+				t.timingestimate()
+			case 'w':
+				t.lintlist()
+			case 'o':
+				t.symbolfinder()
+			case 'F':
+				t.filtermenu()
+			case 'z':
+				t.togglefold()
+			case 'e':
+				t.foldouter()
+			case 'Z':
+				t.foldall()
+			case 'U':
+				t.unfoldall()
+			case 'S':
+				t.toggleinterleaved()
+			case 'B':
+				t.togglebanding()
+			case 'D':
+				t.toggledemangling()
+			case 'x':
+				t.togglevariables()
 			case 'v':
 				if t.opensourcefile() {
 					t.Resize()
-					_, line := t.topmodel.GetPosition(t.toptopline + t.topcursor)
+					_, line := t.topmodel.GetPosition(t.cursortop())
 					if line > 0 {
 						t.middlemarked[line] = true
 						t.showlinemiddle(line)
@@ -1199,6 +1410,17 @@ main:
 				t.bottom.NoutRefresh()
 				gc.Update()
 			}
+			// keep a second assembler panel in sync after moving
+			if t.other != nil {
+				switch input {
+				case gc.KEY_DOWN, 'j', gc.KEY_UP, 'k', gc.KEY_PAGEDOWN, gc.KEY_PAGEUP, gc.KEY_HOME, gc.KEY_END, 'G', 'n', 'p':
+					if t.focus == 0 {
+						t.syncother()
+					} else {
+						t.syncsource()
+					}
+				}
+			}
 		}
 	}
 	gc.End()
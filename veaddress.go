@@ -0,0 +1,172 @@
+package main
+
+/*
+	VE address resolution
+
+	VE builds 64 bit addresses of symbols in two steps:
+		lea	%s12,printf@plt_lo(-24)
+		and	%s12,%s12,(32)0
+		sic	%s16
+		lea.sl	%s12,printf@plt_hi(%s16,%s12)
+		bsic	%lr,(,%s12)
+	lea loads the lower 32 bit, lea.sl adds the upper 32 bit.
+	we match both halves by symbol and remember which register
+	holds which symbol, until the register is written again, a
+	label is reached or a call clobbers registers. addresses of
+	GOT entries become the symbol when they are loaded with ld.
+	lines defining or using such a register are annotated, and
+	calls with bsic and data references can be followed.
+*/
+
+import (
+	"regexp"
+	"strings"
+)
+
+// RegisterSymbol is a register holding the address of a symbol
+type RegisterSymbol struct {
+	register string // canonical register
+	symbol   string
+	reloc    string // relocation without _lo/_hi: "", pc, got, gotoff, plt, tpoff, ...
+}
+
+// relocations like sym@lo, sym@pc_hi or sym@plt_lo(-24)
+var rereloc = regexp.MustCompile(`^([^@(,\s]+)@(?:([a-z_]+)_)?(lo|hi)\b`)
+
+// text returns what the register holds, like printf@plt
+func (r RegisterSymbol) text() string {
+	switch r.reloc {
+	case "", "pc", "gotoff":
+		return r.symbol
+	case "got":
+		return "GOT entry of " + r.symbol
+	}
+	return r.symbol + "@" + r.reloc
+}
+
+// parsereloc returns symbol, relocation and half of a relocated operand
+func parsereloc(operand string) (string, string, string, bool) {
+	m := rereloc.FindStringSubmatch(operand)
+	if m == nil {
+		return "", "", "", false
+	}
+	return m[1], m[2], m[3], true
+}
+
+// resolveaddresses finds the registers holding symbol addresses on each line (VE)
+func (a *AssemblerFile) resolveaddresses() {
+	a.addresses = make(map[int][]RegisterSymbol)
+	if !a.ve {
+		return
+	}
+	lower := make(map[string]RegisterSymbol) // registers holding the lower half of an address
+	held := make(map[string]RegisterSymbol)  // registers holding an address
+	add := func(line int, r RegisterSymbol) {
+		for _, s := range a.addresses[line] {
+			if s.register == r.register {
+				return
+			}
+		}
+		a.addresses[line] = append(a.addresses[line], r)
+	}
+	for l := 1; l < len(a.index); l++ {
+		line := a.filebuffer.GetLine(l)
+		if len(line) > 0 && line[0] != ' ' && line[0] != '\t' && labelof(line) != "" {
+			lower = make(map[string]RegisterSymbol)
+			held = make(map[string]RegisterSymbol)
+			continue
+		}
+		ins, ok := parseInstruction(line)
+		if !ok {
+			continue
+		}
+		for _, r := range usedregisters(ins, true) {
+			if s, ok := held[r]; ok {
+				add(l, s)
+			}
+		}
+		def := definedregister(ins, true)
+		if def != "" && len(ins.operands) == 2 {
+			switch ins.mnemonic {
+			case "lea":
+				if symbol, reloc, half, ok := parsereloc(ins.operands[1]); ok && half == "lo" {
+					delete(held, def)
+					lower[def] = RegisterSymbol{def, symbol, reloc}
+					continue
+				}
+			case "lea.sl":
+				if symbol, reloc, half, ok := parsereloc(ins.operands[1]); ok && half == "hi" {
+					matched := false
+					for _, r := range registersin(ins.operands[1]) {
+						low, ok := lower[canonicalregister(r, true)]
+						matched = matched || ok && low.symbol == symbol && low.reloc == reloc
+					}
+					delete(lower, def)
+					delete(held, def)
+					if matched {
+						held[def] = RegisterSymbol{def, symbol, reloc}
+						add(l, held[def])
+					}
+					continue
+				}
+			case "ld":
+				if m, ok := parsememory(ins.operands[1], true); ok && m.base != "" {
+					if s, ok := held[canonicalregister(m.base, true)]; ok && s.reloc == "got" {
+						held[def] = RegisterSymbol{def, s.symbol, ""}
+						add(l, held[def])
+						continue
+					}
+				}
+			}
+		}
+		if ins.basemnemonic() == "and" && def != "" {
+			if _, ok := lower[def]; ok {
+				continue // clears the upper half of the lower part
+			}
+		}
+		if ins.basemnemonic() == "bsic" {
+			// calls clobber registers
+			lower = make(map[string]RegisterSymbol)
+			held = make(map[string]RegisterSymbol)
+			continue
+		}
+		if def != "" {
+			delete(lower, def)
+			delete(held, def)
+		}
+	}
+}
+
+// addressesof annotates the registers holding symbol addresses in line, like %s12 = printf@plt
+func (a *AssemblerFile) addressesof(line int) string {
+	annotations := make([]string, 0, 1)
+	for _, s := range a.addresses[line] {
+		annotations = append(annotations, s.register+" = "+s.text())
+	}
+	return strings.Join(annotations, ", ")
+}
+
+// referenceof returns the line of the label an instruction calls or refers to,
+// through a register holding its address or directly, 0 if there is none in the file
+func (a *AssemblerFile) referenceof(line int) int {
+	for _, s := range a.addresses[line] {
+		if l, ok := a.labels[s.symbol]; ok {
+			return l
+		}
+	}
+	ins, ok := parseInstruction(a.filebuffer.GetLine(line))
+	if !ok {
+		return 0
+	}
+	for _, operand := range ins.operands {
+		for _, ref := range resymbolref.FindAllString(operand, -1) {
+			if pos := strings.IndexByte(ref, '@'); pos > 0 {
+				ref = ref[:pos]
+			}
+			if l, ok := a.labels[ref]; ok {
+				return l
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,78 @@
+package main
+
+/*
+	tests of the VE address resolution
+*/
+
+import "testing"
+
+func TestParseReloc(t *testing.T) {
+	tests := []struct {
+		operand, symbol, reloc, half string
+		ok                           bool
+	}{
+		{".LC0@lo", ".LC0", "", "lo", true},
+		{".LC0@hi(,%s0)", ".LC0", "", "hi", true},
+		{"printf@plt_lo(-24)", "printf", "plt", "lo", true},
+		{"printf@plt_hi(%s16,%s12)", "printf", "plt", "hi", true},
+		{"x@got_hi(%s15,%s1)", "x", "got", "hi", true},
+		{"y@pc_lo(-24)", "y", "pc", "lo", true},
+		{"256", "", "", "", false},
+		{"-8(,%fp)", "", "", "", false},
+	}
+	for _, test := range tests {
+		symbol, reloc, half, ok := parsereloc(test.operand)
+		if symbol != test.symbol || reloc != test.reloc || half != test.half || ok != test.ok {
+			t.Errorf("parsereloc(%q) = %q, %q, %q, %v", test.operand, symbol, reloc, half, ok)
+		}
+	}
+	got := RegisterSymbol{"%s1", "x", "got"}
+	if got.text() != "GOT entry of x" {
+		t.Errorf("text = %q", got.text())
+	}
+}
+
+func TestResolveAddresses(t *testing.T) {
+	a := testfile(t, vesample+`
+	.text
+	.type	global,@function
+global:
+	lea	%s1,x@got_lo(-24)
+	and	%s1,%s1,(32)0
+	sic	%s15
+	lea.sl	%s1,x@got_hi(%s15,%s1)
+	ld	%s1,(,%s1)
+	ld	%s2,8(,%s1)
+	lea	%s3,.LC0@lo
+	or	%s3,0,%s0
+	lea.sl	%s3,.LC0@hi(,%s3)
+	b.l.t	(,%lr)
+	.size	global,.-global
+`)
+	tests := []struct {
+		line, addresses string
+	}{
+		{"lea.sl %s0,.LC0@hi(,%s0)", "%s0 = .LC0"},
+		{"ld %s1,0(,%s0)", "%s0 = .LC0"},
+		{"lea.sl %s12,printf@plt_hi(%s16,%s12)", "%s12 = printf@plt"},
+		{"bsic %lr,(,%s12)", "%s12 = printf@plt"},
+		{"lea.sl %s12,helper@hi(,%s12)", "%s12 = helper"},
+		{"fdiv.d %s3,%s3,%s1", ""}, // the call clobbered %s1
+		{"lea.sl %s1,x@got_hi(%s15,%s1)", "%s1 = GOT entry of x"},
+		{"ld %s1,(,%s1)", "%s1 = GOT entry of x"},
+		{"ld %s2,8(,%s1)", "%s1 = x"},
+		{"lea.sl %s3,.LC0@hi(,%s3)", ""}, // lower half overwritten
+	}
+	for _, test := range tests {
+		if text := a.addressesof(linewith(t, a, test.line)); text != test.addresses {
+			t.Errorf("addresses of %q = %q, want %q", test.line, text, test.addresses)
+		}
+	}
+	if l := a.referenceof(linewith(t, a, "ld %s1,0(,%s0)")); l != linewith(t, a, ".LC0:") {
+		t.Errorf("reference of the load is line %d", l)
+	}
+	bsic := linewith(t, a, "lea.sl %s12,helper@hi(,%s12)") + 1
+	if l := a.referenceof(bsic); l != linewith(t, a, "helper:") {
+		t.Errorf("reference of the call in line %d is line %d", bsic, l)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+/*
+	VE vectorization quality per loop
+
+	for each loop, the instruction classes are counted, vector
+	loads and stores are split by their stride into contiguous,
+	constant strided and register strided accesses.
+	the score is the share of vector work in all work, where
+	strided accesses count 3/4 and gathers/scatters count 1/4:
+
+		score = 100 * (vector + mask + contiguous + 0.75 * strided + 0.25 * (gather + scatter))
+		            / (all instructions without branches and calls)
+
+	so a loop of only contiguous vector code gets 100, scalar loops get 0.
+*/
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LoopMix counts the instruction classes inside a loop
+type LoopMix struct {
+	classes    [nrclasses]int
+	contiguous int // vector loads/stores with stride equal to element size
+	strided    int // vector loads/stores with other constant stride
+	varstride  int // vector loads/stores with stride in a register
+}
+
+// elementsize returns the size of elements for a vector load or store
+func elementsize(ins Instruction) int64 {
+	base := strings.TrimSuffix(ins.basemnemonic(), "2d")
+	if strings.HasSuffix(base, "u") || strings.HasSuffix(base, "l") {
+		return 4
+	}
+	return 8
+}
+
+// loopmix counts the instructions of a loop, including nested loops
+func (a *AssemblerFile) loopmix(li int, blocks []Block) LoopMix {
+	var mix LoopMix
+	loop := a.loops[li]
+	for l := loop.head; l <= loop.latch; l++ {
+		ins, ok := parseInstruction(a.filebuffer.GetLine(l))
+		if !ok {
+			continue
+		}
+		class := classifyVE(ins)
+		mix.classes[class]++
+		if (class == classVectorLoad || class == classVectorStore) && len(ins.operands) > 1 {
+			stride := a.evalregister(blocks, l, ins.operands[1], 0)
+			if stride.state != vlConst {
+				mix.varstride++
+			} else if stride.value == elementsize(ins) {
+				mix.contiguous++
+			} else {
+				mix.strided++
+			}
+		}
+	}
+	return mix
+}
+
+// gathers returns the number of gathers and scatters
+func (m LoopMix) gathers() int {
+	return m.classes[classGather] + m.classes[classScatter]
+}
+
+// scalars returns the number of scalar instructions without branches and calls
+func (m LoopMix) scalars() int {
+	return m.classes[classScalar] + m.classes[classScalarFP] + m.classes[classLoad] + m.classes[classStore]
+}
+
+// score returns the vectorization quality between 0 and 100
+func (m LoopMix) score() float64 {
+	all := m.scalars() + m.classes[classVector] + m.classes[classMask] +
+		m.classes[classVectorLoad] + m.classes[classVectorStore] + m.gathers()
+	if all == 0 {
+		return 0
+	}
+	weighted := float64(m.classes[classVector]+m.classes[classMask]+m.contiguous) +
+		0.75*float64(m.strided+m.varstride) + 0.25*float64(m.gathers())
+	return 100.0 * weighted / float64(all)
+}
+
+// vectorizationreport returns one list entry per loop with the vectorization quality
+func (a *AssemblerFile) vectorizationreport() []ListEntry {
+	entries := make([]ListEntry, 0, len(a.loops))
+	var blocks []Block
+	lastfunction := -1
+	for li, loop := range a.loops {
+		if loop.function != lastfunction {
+			blocks = a.buildblocks(loop.function)
+			lastfunction = loop.function
+		}
+		mix := a.loopmix(li, blocks)
+		warning := ""
+		if loop.inner && mix.gathers() > 0 {
+			warning = "  ! gather/scatter in inner loop"
+		}
+		name := strings.Repeat(" ", loop.depth-1) + a.loopname(li)
+		entries = append(entries, ListEntry{fmt.Sprintf("%4.0f%% %5d %4d %4d %4d %3d %3d %4d %6d  %-20s %s%s",
+			mix.score(), mix.classes[classVector], mix.contiguous, mix.strided, mix.varstride,
+			mix.classes[classGather], mix.classes[classScatter], mix.classes[classMask], mix.scalars(),
+			name, symbolname(a.functions[loop.function].name), warning), loop.head})
+	}
+	return entries
+}
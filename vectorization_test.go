@@ -0,0 +1,69 @@
+package main
+
+/*
+	tests of the VE instruction classes and the vectorization report
+*/
+
+import "testing"
+
+func TestClassifyVE(t *testing.T) {
+	tests := []struct {
+		line  string
+		class int
+	}{
+		{"\tadds.l\t%s2,%s2,%s3", classScalar},
+		{"\tfdiv.d\t%s3,%s3,%s1", classScalarFP},
+		{"\tld\t%s18,-8(,%fp)", classLoad},
+		{"\tst\t%s2,-16(,%fp)", classStore},
+		{"\tbrlt.l\t%s2,%s0,.L1.0", classBranch},
+		{"\tbsic\t%lr,(,%s12)", classCall},
+		{"\tvfmad.d\t%v2,%v1,%s18,%v0", classVector},
+		{"\tvld\t%v0,8,%s7", classVectorLoad},
+		{"\tvst\t%v2,8,%s7", classVectorStore},
+		{"\tvgt\t%v1,%v0,0,0", classGather},
+		{"\tvsc\t%v4,%v0,0,0", classScatter},
+		{"\tvfmk.l.gt\t%vm1,%v0", classMask},
+	}
+	for _, test := range tests {
+		ins, _ := parseInstruction(test.line)
+		if class := classifyVE(ins); class != test.class {
+			t.Errorf("class of %q = %s, want %s", test.line, classnames[class], classnames[test.class])
+		}
+	}
+}
+
+func TestLoopMix(t *testing.T) {
+	a := testfile(t, vesample)
+	if len(a.loops) != 1 {
+		t.Fatalf("%d loops, want 1", len(a.loops))
+	}
+	mix := a.loopmix(0, a.buildblocks(a.loops[0].function))
+	// vld, vldl.sx and vst with the element size as stride
+	if mix.contiguous != 3 || mix.strided != 0 || mix.varstride != 0 {
+		t.Errorf("%d contiguous, %d strided, %d varying strides, want 3, 0, 0", mix.contiguous, mix.strided, mix.varstride)
+	}
+	if mix.classes[classGather] != 1 || mix.classes[classScatter] != 1 || mix.classes[classMask] != 1 {
+		t.Errorf("%d gathers, %d scatters, %d masks, want 1, 1, 1",
+			mix.classes[classGather], mix.classes[classScatter], mix.classes[classMask])
+	}
+	if mix.classes[classVector] != 2 || mix.scalars() != 9 {
+		t.Errorf("%d vector and %d scalar instructions, want 2 and 9", mix.classes[classVector], mix.scalars())
+	}
+}
+
+func TestScore(t *testing.T) {
+	var mix LoopMix
+	if mix.score() != 0 {
+		t.Errorf("score of empty loop = %v, want 0", mix.score())
+	}
+	mix.classes[classVector] = 2
+	mix.classes[classVectorLoad] = 2
+	mix.contiguous = 1
+	mix.strided = 1
+	mix.classes[classGather] = 1
+	mix.classes[classScalar] = 3
+	// (2 + 1 + 0.75 + 0.25) / 8
+	if mix.score() != 50 {
+		t.Errorf("score = %v, want 50", mix.score())
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+/*
+	rows of the top panel
+
+	without any display mode, row n of the top panel is line n of the
+	assembler file. display modes like the interleaved source view
+	insert rows which are not lines of the file, so toptopline and
+	topcursor are row coordinates, and everything that works on the
+	file (marks, search, analysis) converts with linetop and rowtop.
+
+	in the interleaved view, the source line is shown as header
+	whenever the location of the assembler lines changes, like
+	objdump -S does. lines hidden by filters (see filter.go) get
+	no row, folds (see fold.go) get one row for all their lines.
+*/
+
+import (
+	"fmt"
+	"sort"
+
+	gc "github.com/rthornton128/goncurses"
+)
+
+// viewrow is one row of the top panel, a line of the assembler file, a header in front of it or a fold
+type viewrow struct {
+	line   int  // assembler line, for headers the line the header belongs to, for folds the first line
+	header bool // row is a source header
+	end    int  // last line of a fold, 0 if row is no fold
+}
+
+// sourcecache keeps the source files read for headers, nil for files which can not be read
+var sourcecache = make(map[string]*Sourcefile)
+
+// nrrowstop returns the number of rows of the top panel
+func (t *TuiT) nrrowstop() int {
+	if t.toprows == nil {
+		return t.topmodel.GetNrLines()
+	}
+	return len(t.toprows) - 1
+}
+
+// linetop returns the assembler line of a row and if the row is a header
+func (t *TuiT) linetop(row int) (int, bool) {
+	if t.toprows == nil {
+		return row, false
+	}
+	if row < 1 {
+		return 0, false
+	}
+	if row >= len(t.toprows) {
+		return row - len(t.toprows) + t.topmodel.GetNrLines() + 1, false // behind end of file
+	}
+	return t.toprows[row].line, t.toprows[row].header
+}
+
+// rowtop returns the row of an assembler line
+func (t *TuiT) rowtop(line int) int {
+	if t.toprows == nil {
+		return line
+	}
+	row := sort.Search(len(t.toprows), func(i int) bool {
+		return t.toprows[i].line > line || t.toprows[i].line == line && !t.toprows[i].header
+	})
+	// lines inside of a fold are on the row of the fold
+	if row > 0 && t.toprows[row-1].end >= line {
+		return row - 1
+	}
+	if row >= len(t.toprows) {
+		return len(t.toprows) - 1
+	}
+	return row
+}
+
+// viewrowtop returns the row of the top panel
+func (t *TuiT) viewrowtop(row int) viewrow {
+	if t.toprows == nil || row < 1 || row >= len(t.toprows) {
+		line, _ := t.linetop(row)
+		return viewrow{line: line}
+	}
+	return t.toprows[row]
+}
+
+// cursortop returns the assembler line of the cursor, for headers the line they belong to
+func (t *TuiT) cursortop() int {
+	line, _ := t.linetop(t.toptopline + t.topcursor)
+	return line
+}
+
+// buildrowstop computes the rows for the current display mode, nil if rows are lines
+func (t *TuiT) buildrowstop() {
+	hidden := t.hiddentop()
+	if !t.interleaved && hidden == nil && len(t.folds) == 0 {
+		t.toprows = nil
+		return
+	}
+	nrlines := t.topmodel.GetNrLines()
+	rows := make([]viewrow, 1, nrlines+nrlines/4+1) // row 0 is unused
+	last := loctuple{}
+	for l := 1; l <= nrlines; l++ {
+		if fold, ok := t.folds[l]; ok && fold.end >= l {
+			// folds hidden completely by filters get no row
+			visible := hidden == nil
+			for f := l; f <= fold.end && !visible; f++ {
+				visible = f >= len(hidden) || !hidden[f]
+			}
+			if visible {
+				rows = append(rows, viewrow{l, false, fold.end})
+			}
+			if fold.end < len(assemblerfile.index) {
+				last = assemblerfile.index[fold.end].loc
+			}
+			l = fold.end
+			continue
+		}
+		if l < len(hidden) && hidden[l] {
+			continue
+		}
+		if t.interleaved && l < len(assemblerfile.index) {
+			if loc := assemblerfile.index[l].loc; loc.linenr > 0 && loc != last {
+				rows = append(rows, viewrow{l, true, 0})
+				last = loc
+			}
+		}
+		rows = append(rows, viewrow{l, false, 0})
+	}
+	t.toprows = rows
+}
+
+// headertext returns the source line shown as header in front of an assembler line
+func (t *TuiT) headertext(line int) string {
+	filename, linenr := t.topmodel.GetPosition(line)
+	source, ok := sourcecache[filename]
+	if !ok {
+		var err error
+		source, err = NewSourceFile(filename)
+		if err != nil {
+			source = nil
+		}
+		sourcecache[filename] = source
+	}
+	if source != nil && linenr <= source.filebuffer.GetNrLines() {
+		return fmt.Sprintf("%7d: %s", linenr, source.filebuffer.GetLine(linenr))
+	}
+	return fmt.Sprintf("%7d: <%s>", linenr, filename)
+}
+
+// drawheadertop draws a source header in row y of the top panel, y in screen coordinates
+func (t *TuiT) drawheadertop(y, line, offset int) {
+	text := t.headertext(line)
+	if len(text) > t.topwidth-offset {
+		text = text[:maxi(0, t.topwidth-offset)]
+	}
+	t.top.ColorOn(3)
+	if t.banding {
+		t.top.ColorOn(bandtop(line))
+	}
+	t.top.AttrOn(gc.A_DIM)
+	if y == t.topcursor {
+		t.top.AttrOn(gc.A_BOLD)
+	}
+	t.top.MovePrint(y, offset, text)
+	t.top.AttrOff(gc.A_DIM)
+	t.top.AttrOff(gc.A_BOLD)
+	t.top.ClearToEOL()
+}
+
+// toggleinterleaved switches the interleaved source view on or off, keeps the cursor line
+func (t *TuiT) toggleinterleaved() {
+	line := t.cursortop()
+	t.interleaved = !t.interleaved
+	t.buildrowstop()
+	t.top.Erase()
+	t.showlinetop(line)
+}
@@ -0,0 +1,235 @@
+package main
+
+/*
+	VE vector length tracking
+
+	vector instructions work on the active vector length %vl,
+	which is set by lvl. we propagate the values of lvl along
+	the control flow of a function, so for each vector instruction
+	we know the constant, symbolic or varying length it uses.
+	register sources of lvl are resolved through the def-use
+	information, this covers the typical strip mining code
+		mins.l	%s5,%s4,%s3     (%s3 = 256)
+		lvl	%s5
+	which gives VL <= 256.
+*/
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	vlTop      = iota // not yet computed
+	vlUnknown         // not set in this function, or destroyed by a call
+	vlConst           // known constant
+	vlSymbolic        // value of a register, maybe with upper bound
+	vlVaries          // different values on different paths
+)
+
+// VLValue is what is known about the vector length at an instruction
+type VLValue struct {
+	state int
+	value int64  // constant value, or upper bound of symbolic value (0 = no bound)
+	expr  string // symbolic expression, like %s5 or min(%s4,256)
+	def   int    // line of lvl setting it, 0 if not known
+}
+
+// equal compares two VL values, constants are equal independent of where they are set
+func (v VLValue) equal(o VLValue) bool {
+	if v.state != o.state {
+		return false
+	}
+	switch v.state {
+	case vlConst:
+		return v.value == o.value
+	case vlSymbolic:
+		return v.expr == o.expr && v.def == o.def
+	}
+	return true
+}
+
+// meet combines VL values of two paths
+func (v VLValue) meet(o VLValue) VLValue {
+	if v.state == vlTop {
+		return o
+	}
+	if o.state == vlTop || v.equal(o) {
+		return v
+	}
+	if v.state == vlUnknown || o.state == vlUnknown {
+		return VLValue{state: vlUnknown}
+	}
+	return VLValue{state: vlVaries, value: maxi64(v.value, o.value)}
+}
+
+// String returns a long description for the bottom panel
+func (v VLValue) String() string {
+	switch v.state {
+	case vlConst:
+		return fmt.Sprintf("VL = %d", v.value)
+	case vlSymbolic:
+		if v.value > 0 {
+			return fmt.Sprintf("VL = %s <= %d", v.expr, v.value)
+		}
+		return "VL = " + v.expr
+	case vlVaries:
+		return "VL varies on different paths"
+	}
+	return "VL unknown"
+}
+
+// short returns a short description for the gutter
+func (v VLValue) short() string {
+	switch v.state {
+	case vlConst:
+		return fmt.Sprintf("%d", v.value)
+	case vlSymbolic:
+		if v.value > 0 {
+			return fmt.Sprintf("<=%d", v.value)
+		}
+		return v.expr
+	case vlVaries:
+		return "*"
+	}
+	return "?"
+}
+
+// isvector checks for VE instructions using the vector length
+func isvector(ins Instruction) bool {
+	return ins.mnemonic[0] == 'v' || strings.HasPrefix(ins.mnemonic, "pv")
+}
+
+// evalregister tries to find the value of a scalar register at line
+func (a *AssemblerFile) evalregister(blocks []Block, line int, reg string, depth int) VLValue {
+	symbolic := VLValue{state: vlSymbolic, expr: reg}
+	if depth > 8 {
+		return symbolic
+	}
+	if v, ok := parseint(reg); ok {
+		return VLValue{state: vlConst, value: v}
+	}
+	if len(reg) == 0 || reg[0] != '%' {
+		return symbolic
+	}
+	d := a.reachingdef(blocks, line, reg)
+	if d == -1 {
+		return symbolic
+	}
+	ins, _ := parseInstruction(a.filebuffer.GetLine(d))
+	ops := ins.operands
+	switch ins.basemnemonic() {
+	case "lea":
+		if len(ops) == 2 {
+			if v, ok := parseint(ops[1]); ok {
+				return VLValue{state: vlConst, value: v}
+			}
+		}
+	case "or":
+		// or %sx, I, (0)1 loads a constant, or %sx, 0, %sy copies
+		if len(ops) == 3 {
+			if ops[2] == "(0)1" {
+				return a.evalregister(blocks, d, ops[1], depth+1)
+			}
+			if ops[1] == "0" {
+				return a.evalregister(blocks, d, ops[2], depth+1)
+			}
+		}
+	case "mins":
+		if len(ops) == 3 {
+			v1 := a.evalregister(blocks, d, ops[1], depth+1)
+			v2 := a.evalregister(blocks, d, ops[2], depth+1)
+			if v1.state == vlConst && v2.state == vlConst {
+				return VLValue{state: vlConst, value: mini64(v1.value, v2.value)}
+			}
+			bound := int64(0)
+			for _, v := range []VLValue{v1, v2} {
+				if (v.state == vlConst || v.state == vlSymbolic) && v.value > 0 && (bound == 0 || v.value < bound) {
+					bound = v.value
+				}
+			}
+			return VLValue{state: vlSymbolic, value: bound, expr: fmt.Sprintf("min(%s,%s)", v1.short(), v2.short())}
+		}
+	}
+	return symbolic
+}
+
+// tracevl propagates the vector length through all functions
+func (a *AssemblerFile) tracevl() {
+	a.vl = make(map[int]VLValue)
+	for fi := range a.functions {
+		blocks := a.buildblocks(fi)
+		if len(blocks) == 0 {
+			continue
+		}
+		in := make([]VLValue, len(blocks))
+		out := make([]VLValue, len(blocks))
+		in[0] = VLValue{state: vlUnknown}
+
+		// transfer function of a block, records VL of vector instructions if record is set
+		transfer := func(bi int, v VLValue, record bool) VLValue {
+			for l := blocks[bi].start; l <= blocks[bi].end; l++ {
+				ins, ok := parseInstruction(a.filebuffer.GetLine(l))
+				if !ok {
+					continue
+				}
+				switch {
+				case ins.mnemonic == "lvl" && len(ins.operands) == 1:
+					v = a.evalregister(blocks, l, ins.operands[0], 0)
+					v.def = l
+				case ins.mnemonic == "bsic":
+					v = VLValue{state: vlUnknown}
+				case record && isvector(ins):
+					a.vl[l] = v
+				}
+			}
+			return v
+		}
+
+		// iterate until nothing changes, the lattice is flat so this terminates
+		changed := true
+		for iter := 0; changed && iter < 100; iter++ {
+			changed = false
+			for bi := range blocks {
+				v := in[bi]
+				for _, p := range blocks[bi].preds {
+					v = v.meet(out[p])
+				}
+				if bi == 0 {
+					v = v.meet(VLValue{state: vlUnknown})
+				}
+				in[bi] = v
+				o := transfer(bi, v, false)
+				if o.state != out[bi].state || !o.equal(out[bi]) {
+					out[bi] = o
+					changed = true
+				}
+			}
+		}
+		for bi := range blocks {
+			transfer(bi, in[bi], true)
+		}
+	}
+}
+
+// vlof returns the vector length used by instruction in line
+func (a *AssemblerFile) vlof(line int) (VLValue, bool) {
+	v, ok := a.vl[line]
+	return v, ok
+}
+
+// min of 2 int64
+func mini64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// max of 2 int64
+func maxi64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,139 @@
+package main
+
+/*
+	tests of the VE vector length tracking, and an assembler file
+	of VE used by tests
+*/
+
+import "testing"
+
+// vesample is a small VE assembler file with a strip mined loop, calls and constants
+const vesample = `	.ident "ncc 3.0.1 (Build 14:00:00 Jan  1 2020)"
+	.file	"ve.c"
+	.file	1	"ve.c"
+	.text
+	.balign 16
+	.globl	daxpy
+	.type	daxpy,@function
+daxpy:
+	.cfi_startproc
+	st	%fp,0x0(,%sp)
+	st	%lr,0x8(,%sp)
+	st	%got,0x18(,%sp)
+	st	%plt,0x20(,%sp)
+	or	%fp,0,%sp
+	lea	%s13,-192
+	and	%s13,%s13,(32)0
+	lea.sl	%sp,-1(%s13,%sp)
+	.loc	1 4 0
+	st	%s18,-8(,%fp)
+	or	%s2,0,(0)1
+	lea	%s3,256
+.L1.0:
+	.loc	1 5 0
+	subs.l	%s4,%s0,%s2
+	mins.l	%s5,%s4,%s3
+	lvl	%s5
+	sll	%s6,%s2,3
+	adds.l	%s7,%s1,%s6
+	vld	%v0,8,%s7
+	ld	%s18,-8(,%fp)
+	vldl.sx	%v3,4,%s7
+	vgt	%v1,%v0,0,0
+	vfmad.d	%v2,%v1,%s18,%v0
+	vfmk.l.gt	%vm1,%v0
+	vfadd.d	%v4,%v2,%v1,%vm1
+	vst	%v2,8,%s7
+	vsc	%v4,%v0,0,0
+	st	%s2,-16(,%fp)
+	adds.l	%s2,%s2,%s3
+	ld	%s2,-16(,%fp)
+	brlt.l	%s2,%s0,.L1.0
+	.loc	1 7 0
+	lea	%s0,.LC0@lo
+	and	%s0,%s0,(32)0
+	lea.sl	%s0,.LC0@hi(,%s0)
+	ld	%s1,0(,%s0)
+	lea	%s12,printf@plt_lo(-24)
+	and	%s12,%s12,(32)0
+	sic	%s16
+	lea.sl	%s12,printf@plt_hi(%s16,%s12)
+	bsic	%lr,(,%s12)
+	lea	%s12,helper@lo
+	and	%s12,%s12,(32)0
+	lea.sl	%s12,helper@hi(,%s12)
+	bsic	%lr,(,%s12)
+	fdiv.d	%s3,%s3,%s1
+	ld	%s18,-8(,%fp)
+	ld	%fp,0x0(,%sp)
+	ld	%lr,0x8(,%sp)
+	b.l.t	(,%lr)
+	.cfi_endproc
+	.size	daxpy,.-daxpy
+	.balign 16
+	.type	helper,@function
+helper:
+	.cfi_startproc
+	or	%s0,1,%s0
+	lvl	%s0
+	vfadd.d	%v0,%v0,%v1
+	lea	%s1,64
+	lvl	%s1
+	vfmul.d	%v0,%v0,%v1
+	b.l.t	(,%lr)
+	.cfi_endproc
+	.size	helper,.-helper
+	.section .rodata
+	.balign 8
+.LC0:
+	.long	0
+	.long	1074266112
+.LC1:
+	.byte	104
+	.byte	105
+	.byte	0
+`
+
+func TestVLMeet(t *testing.T) {
+	top := VLValue{state: vlTop}
+	unknown := VLValue{state: vlUnknown}
+	c64 := VLValue{state: vlConst, value: 64, def: 10}
+	c64b := VLValue{state: vlConst, value: 64, def: 20}
+	c128 := VLValue{state: vlConst, value: 128}
+	tests := []struct {
+		v, o, want VLValue
+	}{
+		{top, c64, c64},
+		{c64, top, c64},
+		{c64, c64b, c64},
+		{c64, c128, VLValue{state: vlVaries, value: 128}},
+		{c64, unknown, unknown},
+		{unknown, c128, unknown},
+	}
+	for _, test := range tests {
+		if got := test.v.meet(test.o); got.state != test.want.state || !got.equal(test.want) || got.value != test.want.value {
+			t.Errorf("%v meet %v = %v, want %v", test.v, test.o, got, test.want)
+		}
+	}
+}
+
+func TestTraceVL(t *testing.T) {
+	a := testfile(t, vesample)
+	tests := []struct {
+		line, vl string
+	}{
+		{"vld", "<=256"},       // mins.l of the remaining length and 256
+		{"vsc", "<=256"},       // same VL until the end of the loop
+		{"vfadd.d %v0", "%s0"}, // argument
+		{"vfmul.d", "64"},
+	}
+	for _, test := range tests {
+		v, ok := a.vlof(linewith(t, a, test.line))
+		if !ok || v.short() != test.vl {
+			t.Errorf("VL of %q = %q, want %q", test.line, v.short(), test.vl)
+		}
+	}
+	if v, _ := a.vlof(linewith(t, a, "vld")); v.String() != "VL = min(%s4,256) <= 256" {
+		t.Errorf("VL of vld = %q", v.String())
+	}
+}